@@ -0,0 +1,148 @@
+package goflag
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Element types used to parse each token of a variadic argument.
+var sliceElemType = map[flagType]flagType{
+	flagStringSlice: flagString,
+	flagIntSlice:    flagInt,
+}
+
+// Create a positional argument and append it to args.
+// A slice argument is variadic and collects all remaining tokens,
+// so it must be the last argument.
+func addArg(args []*Flag, name string, valuePtr any, usage string) ([]*Flag, *Flag) {
+	arg := &Flag{
+		name:       name,
+		value:      valuePtr,
+		usage:      usage,
+		positional: true,
+	}
+	validateFlag(arg)
+
+	flagType, ok := flagTypeOf(valuePtr)
+	if !ok {
+		panic(fmt.Errorf("unsupported type %T for argument %s", valuePtr, name))
+	}
+	arg.flagType = flagType
+	_, arg.variadic = sliceElemType[flagType]
+
+	if len(args) > 0 && args[len(args)-1].variadic {
+		panic(fmt.Errorf("argument %s can not follow variadic argument %s", name, args[len(args)-1].name))
+	}
+	return append(args, arg), arg
+}
+
+// Bind the n-th positional token to its declared argument.
+// Tokens beyond the declared arguments are appended to a variadic tail
+// or rejected. If no arguments are declared, tokens are ignored.
+func parseArg(args []*Flag, n int, value string) error {
+	if len(args) == 0 {
+		return nil
+	}
+
+	var arg *Flag
+	if n < len(args) {
+		arg = args[n]
+	} else if last := args[len(args)-1]; last.variadic {
+		arg = last
+	} else {
+		return fmt.Errorf("unexpected argument: %s", value)
+	}
+
+	var err error
+	if arg.variadic {
+		// Replace the default on the first token instead of appending to it.
+		if n == len(args)-1 {
+			slice := reflect.ValueOf(arg.value).Elem()
+			slice.Set(reflect.Zero(slice.Type()))
+		}
+		err = appendSliceValue(arg, value)
+	} else {
+		err = parseFlagValue(arg, value)
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid argument <%s>: %w", arg.name, err)
+	}
+
+	if err := validateFlagValue(arg); err != nil {
+		return err
+	}
+	arg.source = SourceArgv
+	return nil
+}
+
+// Bind the n-th positional token that followed the -- terminator.
+// Unlike parseArg, tokens beyond the declared arguments are not an error,
+// they remain available through Rest.
+func bindRestArg(args []*Flag, n int, value string) error {
+	if n >= len(args) && (len(args) == 0 || !args[len(args)-1].variadic) {
+		return nil
+	}
+	return parseArg(args, n, value)
+}
+
+// Parse a single element of a slice flag or variadic argument and append it.
+func appendSliceValue(arg *Flag, value string) error {
+	elem, err := parseSliceElem(arg, value)
+	if err != nil {
+		return err
+	}
+
+	slice := reflect.ValueOf(arg.value).Elem()
+	slice.Set(reflect.Append(slice, elem))
+	return nil
+}
+
+// Check that all required arguments received a value.
+// n is the number of positional tokens that were parsed.
+func checkRequiredArgs(args []*Flag, n int) error {
+	for i, arg := range args {
+		if i >= n && arg.required {
+			return fmt.Errorf("missing required argument <%s>", arg.name)
+		}
+	}
+	return nil
+}
+
+// Returns the usage synopsis of the arguments. e.g <src> <dst> [files...]
+func argsSynopsis(args []*Flag) string {
+	parts := make([]string, 0, len(args))
+	for _, arg := range args {
+		name := arg.name
+		if arg.variadic {
+			name += "..."
+		}
+
+		if arg.required {
+			parts = append(parts, "<"+name+">")
+		} else {
+			parts = append(parts, "["+name+"]")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Print the positional arguments to the writer.
+func printArgs(args []*Flag, w io.Writer, indent string) {
+	longestArgName := 0
+	for _, arg := range args {
+		if len(arg.name) > longestArgName {
+			longestArgName = len(arg.name)
+		}
+	}
+
+	for _, arg := range args {
+		fmt.Fprintf(w, "%s%-*s  %s", indent, longestArgName, arg.name, arg.usageText())
+		if arg.required {
+			fmt.Fprint(w, " (required)")
+		}
+		fmt.Fprintln(w)
+	}
+}
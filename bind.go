@@ -0,0 +1,173 @@
+package goflag
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Runner is implemented by structs bound as subcommands with Bind.
+// Run is called as the context-aware handler of the subcommand.
+type Runner interface {
+	Run(ctx context.Context, cmd *Command) error
+}
+
+// Bind registers a flag on a CLI or subcommand for every exported field of
+// the struct pointed to by opts. Parse stores the values in the fields.
+//
+// Fields are configured with struct tags:
+//
+//	flag:"port"       the flag name. Defaults to the field name in kebab-case. "-" skips the field.
+//	short:"p"         the short name.
+//	usage:"..."       the usage, or the description of a subcommand.
+//	env:"PORT"        the environment variable, see Flag.Env.
+//	default:"8080"    the default value, parsed like a command line value.
+//	required:"true"   the flag must be given.
+//	choices:"a,b"     the allowed values, see Flag.Enum. Each element of a slice must be a choice.
+//	arg:"src"         bind the field as a positional argument instead of a flag.
+//	command:"serve"   bind a struct field as a subcommand. Its fields are its flags.
+//	prefix:"db-"      prefix the flag names of a nested struct that is a flag group.
+//
+// A struct field that is not a supported flag type is a flag group: its fields
+// are registered on the same CLI or subcommand. Subcommand structs that
+// implement Runner use Run as their handler.
+//
+// It panics if opts is not a pointer to a struct, a field has an unsupported
+// type or a tag is invalid.
+func Bind(set FlagSet, opts any) {
+	v := reflect.ValueOf(opts)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		panic(fmt.Errorf("Bind expects a pointer to a struct, got %T", opts))
+	}
+	bindStruct(set, v.Elem(), "")
+}
+
+// Register the fields of a struct on set. prefix is prepended to flag names.
+func bindStruct(set FlagSet, v reflect.Value, prefix string) {
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() || field.Tag.Get("flag") == "-" {
+			continue
+		}
+
+		fieldValue := v.Field(i)
+		if name := field.Tag.Get("command"); name != "" {
+			bindCommand(set, field, fieldValue, name)
+			continue
+		}
+
+		ptr := fieldValue.Addr().Interface()
+		if name := field.Tag.Get("arg"); name != "" {
+			configureBoundFlag(bindArg(set, name, ptr, field.Tag.Get("usage")), field)
+			continue
+		}
+
+		flagType, ok := flagTypeOf(ptr)
+		if !ok {
+			if field.Type.Kind() == reflect.Struct {
+				bindStruct(set, fieldValue, prefix+field.Tag.Get("prefix"))
+				continue
+			}
+			panic(fmt.Errorf("unsupported type %s for field %s", field.Type, field.Name))
+		}
+
+		name := field.Tag.Get("flag")
+		if name == "" {
+			name = kebabCase(field.Name)
+		}
+
+		flag := set.addFlag(flagType, prefix+name, field.Tag.Get("short"), ptr, field.Tag.Get("usage"))
+		configureBoundFlag(flag, field)
+	}
+}
+
+// Register a struct field as a subcommand of set.
+func bindCommand(set FlagSet, field reflect.StructField, v reflect.Value, name string) {
+	if v.Kind() == reflect.Pointer && v.Type().Elem().Kind() == reflect.Struct {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		panic(fmt.Errorf("command field %s must be a struct, got %s", field.Name, field.Type))
+	}
+
+	usage := field.Tag.Get("usage")
+	if usage == "" {
+		panic(fmt.Errorf("command field %s requires a usage tag", field.Name))
+	}
+
+	handler := func(ctx context.Context, cmd *Command) error { return nil }
+	if runner, ok := v.Addr().Interface().(Runner); ok {
+		handler = runner.Run
+	}
+
+	var cmd *subcommand
+	switch s := set.(type) {
+	case *CLI:
+		cmd = s.Command(name, usage, handler)
+	case *subcommand:
+		cmd = s.Command(name, usage, handler)
+	}
+	bindStruct(cmd, v, "")
+}
+
+// Add a positional argument to set and return it.
+func bindArg(set FlagSet, name string, ptr any, usage string) *Flag {
+	switch s := set.(type) {
+	case *CLI:
+		return s.Arg(name, ptr, usage)
+	case *subcommand:
+		s.Arg(name, ptr, usage)
+		return s.last
+	}
+	return nil
+}
+
+// Apply the env, default, required and choices tags to a bound flag.
+func configureBoundFlag(flag *Flag, field reflect.StructField) {
+	if env := field.Tag.Get("env"); env != "" {
+		flag.Env(env)
+	}
+
+	if def, ok := field.Tag.Lookup("default"); ok {
+		if err := parseFlagValue(flag, def); err != nil {
+			panic(fmt.Errorf("invalid default for field %s: %w", field.Name, err))
+		}
+	}
+
+	if required := field.Tag.Get("required"); required != "" {
+		isRequired, err := strconv.ParseBool(required)
+		if err != nil {
+			panic(fmt.Errorf("invalid required tag for field %s: %s", field.Name, required))
+		}
+		flag.required = isRequired
+	}
+
+	if choices := field.Tag.Get("choices"); choices != "" {
+		flag.Enum(strings.Split(choices, ",")...)
+	}
+}
+
+// Convert a Go identifier to kebab-case. e.g DryRun => dry-run, HTTPPort => http-port
+func kebabCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+				b.WriteByte('-')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
@@ -0,0 +1,173 @@
+package goflag
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type serveOptions struct {
+	Addr    string   `flag:"addr" short:"a" usage:"Address" default:":8080"`
+	Origins []string `usage:"Allowed origins" default:"*"`
+	Mode    string   `usage:"Mode" choices:"dev,prod" default:"dev"`
+
+	ran bool
+}
+
+func (s *serveOptions) Run(ctx context.Context, cmd *Command) error {
+	s.ran = true
+	return nil
+}
+
+type bindOptions struct {
+	Port    int           `flag:"port" short:"p" usage:"Port" env:"BIND_PORT" default:"8080"`
+	DryRun  bool          `short:"n" usage:"Dry run"`
+	Timeout time.Duration `usage:"Timeout" default:"5s"`
+	Levels  []string      `usage:"Levels" choices:"debug,info,warn"`
+	Name    string        `usage:"Name" required:"true"`
+	Ignored string        `flag:"-"`
+	hidden  string
+
+	DB struct {
+		Host string `usage:"Database host" default:"localhost"`
+	} `prefix:"db-"`
+
+	Serve serveOptions `command:"serve" usage:"Start the server"`
+
+	Copy *struct {
+		Src string   `arg:"src" usage:"Source" required:"true"`
+		Dst []string `arg:"dst" usage:"Destinations"`
+	} `command:"copy" usage:"Copy files"`
+}
+
+func TestBind(t *testing.T) {
+	var opts bindOptions
+	cli := New()
+	Bind(cli, &opts)
+
+	if opts.Port != 8080 || opts.Timeout != 5*time.Second || opts.DB.Host != "localhost" {
+		t.Errorf("Expected defaults from tags, got %+v", opts)
+	}
+
+	if findFlag(cli.flags, "ignored") != nil || findFlag(cli.flags, "hidden") != nil {
+		t.Errorf("Expected skipped fields to have no flags")
+	}
+
+	t.Setenv("BIND_PORT", "9090")
+	err := cli.Run(context.Background(), []string{"app", "-n", "--name", "x", "--levels", "debug,warn",
+		"--db-host", "db.local", "serve", "-a", ":9000", "--mode", "prod"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if opts.Port != 9090 || !opts.DryRun || opts.Name != "x" || opts.DB.Host != "db.local" {
+		t.Errorf("Unexpected values: %+v", opts)
+	}
+
+	if !reflect.DeepEqual(opts.Levels, []string{"debug", "warn"}) {
+		t.Errorf("Expected levels [debug warn], but got %v", opts.Levels)
+	}
+
+	if !opts.Serve.ran || opts.Serve.Addr != ":9000" || opts.Serve.Mode != "prod" || !reflect.DeepEqual(opts.Serve.Origins, []string{"*"}) {
+		t.Errorf("Unexpected serve values: %+v", opts.Serve)
+	}
+
+	cmd, err := cli.Parse([]string{"app", "--name", "x", "copy", "a", "b", "c"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if cmd.name != "copy" || opts.Copy.Src != "a" || !reflect.DeepEqual(opts.Copy.Dst, []string{"b", "c"}) {
+		t.Errorf("Unexpected copy values: %+v", opts.Copy)
+	}
+
+	tests := []struct {
+		argv []string
+		want string
+	}{
+		{[]string{"app"}, "missing required flag [- | --name]"},
+		{[]string{"app", "--name", "x", "--levels", "debug,trace"}, "Expected value to be one of: [debug info warn]"},
+		{[]string{"app", "--name", "x", "serve", "--mode", "test"}, "Expected value to be one of: [dev prod]"},
+		{[]string{"app", "--name", "x", "copy"}, "missing required argument <src>"},
+	}
+
+	for _, test := range tests {
+		_, err := cli.Parse(test.argv)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%v: expected error containing %q, but got %v", test.argv, test.want, err)
+		}
+	}
+}
+
+func TestBindChoices(t *testing.T) {
+	var opts bindOptions
+	cli := New().SetName("app")
+	Bind(cli, &opts)
+
+	var out bytes.Buffer
+	cli.PrintUsage(&out)
+	if !strings.Contains(out.String(), "Levels {debug|info|warn}") {
+		t.Errorf("Expected the choices in the help, got:\n%s", out.String())
+	}
+
+	candidates, _ := cli.complete(context.Background(), []string{"serve", "--mode", ""})
+	if !reflect.DeepEqual(candidates, []string{"dev", "prod"}) {
+		t.Errorf("Expected the choices as candidates, got %q", candidates)
+	}
+
+	out.Reset()
+	cli.GenBashCompletion(&out)
+	if !strings.Contains(out.String(), "debug info warn") {
+		t.Errorf("Expected the choices in the bash completion, got:\n%s", out.String())
+	}
+}
+
+func TestBindPanics(t *testing.T) {
+	tests := []struct {
+		opts any
+		want string
+	}{
+		{bindOptions{}, "Bind expects a pointer to a struct"},
+		{&struct{ C complex64 }{}, "unsupported type complex64 for field C"},
+		{&struct {
+			Port int `default:"abc"`
+		}{}, "invalid default for field Port"},
+		{&struct {
+			Port int `required:"yes"`
+		}{}, "invalid required tag for field Port"},
+		{&struct {
+			Serve struct{} `command:"serve"`
+		}{}, "command field Serve requires a usage tag"},
+	}
+
+	for _, test := range tests {
+		func() {
+			defer func() {
+				if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), test.want) {
+					t.Errorf("Expected panic containing %q, but got %v", test.want, r)
+				}
+			}()
+			Bind(New(), test.opts)
+		}()
+	}
+}
+
+func TestKebabCase(t *testing.T) {
+	tests := map[string]string{
+		"Port":       "port",
+		"DryRun":     "dry-run",
+		"HTTPPort":   "http-port",
+		"MaxRetries": "max-retries",
+		"Retry2Max":  "retry2-max",
+	}
+
+	for name, want := range tests {
+		if got := kebabCase(name); got != want {
+			t.Errorf("kebabCase(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
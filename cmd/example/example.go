@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net"
@@ -54,8 +56,14 @@ func printVersion() {
 	}
 }
 
-func handleSleep() {
-	time.Sleep(time.Duration(durationValue) * time.Second)
+// handleSleep sleeps until the duration elapses or the context is canceled.
+func handleSleep(ctx context.Context, cmd *goflag.Command) error {
+	select {
+	case <-time.After(durationValue):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 func handleCors() {
@@ -69,7 +77,7 @@ func main() {
 	log.SetFlags(log.Lshortfile)
 	cli := goflag.New()
 
-	cli.String("config", "c", &config, "Path to config file")
+	cli.String("config", "c", &config, "Path to config file").ConfigFile()
 	cli.Bool("verbose", "v", &verbose, "Enable verbose output")
 	cli.Duration("timeout", "t", &timeout, "Timeout for the request")
 	cli.Int("port", "p", &port, "Port to listen on")
@@ -92,7 +100,7 @@ func main() {
 		Bool("verbose", "v", &verbose, "Enable verbose output").
 		Bool("short", "s", &short, "Print short version")
 
-	cli.SubCommand("sleep", "Sleep for a while", handleSleep).
+	cli.Command("sleep", "Sleep for a while", handleSleep).
 		Duration("time", "t", &durationValue, "Time to sleep in seconds").Required()
 
 	cli.SubCommand("cors", "Enable CORS", handleCors).
@@ -103,12 +111,18 @@ func main() {
 
 	// Parse the command line arguments and return the matching subcommand
 	subcmd, err := cli.Parse(os.Args)
+	if errors.Is(err, goflag.ErrHelpRequested) {
+		os.Exit(0)
+	}
+
 	if err != nil {
 		log.Fatalln(err)
 	}
 
 	if subcmd != nil {
-		subcmd.Handler()
+		if err := subcmd.Run(context.Background()); err != nil {
+			log.Fatalln(err)
+		}
 		os.Exit(0)
 	}
 
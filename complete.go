@@ -0,0 +1,359 @@
+package goflag
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CompletionFunc returns the completion candidates of a flag or argument
+// value that starts with prefix. A candidate may be followed by a tab and
+// its description. e.g "prod\tProduction database"
+//
+// Candidates that do not start with prefix are dropped.
+type CompletionFunc func(ctx context.Context, prefix string) []string
+
+// CompletionDirective tells the shell how to complete the current word
+// in addition to the candidates of the __complete command.
+type CompletionDirective int
+
+const (
+	// Complete file names if there are no candidates.
+	CompletionDefault CompletionDirective = 0
+
+	// Do not complete file names.
+	CompletionNoFile CompletionDirective = 1
+
+	// Complete file names with one of the extensions in the candidates.
+	CompletionFilterExt CompletionDirective = 2
+
+	// Complete directory names only.
+	CompletionFilterDirs CompletionDirective = 4
+)
+
+// The name of the hidden command that computes completion candidates.
+const completeCmdName = "__complete"
+
+// Complete sets the function that completes the values of the flag or
+// argument at runtime. It is called by the __complete command used by
+// the dynamic completion scripts, see GenBashDynamicCompletion.
+func (flag *Flag) Complete(fn CompletionFunc) *Flag {
+	flag.completeFunc = fn
+	return flag
+}
+
+// CompleteFiles completes the values of the flag or argument with the names
+// of files with one of the given extensions, without the leading dot.
+// e.g CompleteFiles("yaml", "yml"). Used by the dynamic completion scripts.
+func (flag *Flag) CompleteFiles(extensions ...string) *Flag {
+	flag.extensions = extensions
+	return flag
+}
+
+// Write the candidates and the directive of the words to complete, one
+// candidate per line followed by the directive. e.g :1
+// words are the args after the program name, the last one is the word
+// under the cursor and may be empty.
+func (c *CLI) writeCompletions(ctx context.Context, w io.Writer, words []string) {
+	candidates, directive := c.complete(ctx, words)
+	for _, candidate := range candidates {
+		fmt.Fprintln(w, candidate)
+	}
+	fmt.Fprintf(w, ":%d\n", directive)
+}
+
+// Returns the completion candidates and directive of the last word.
+// The words before it are scanned like Parse does, without setting any flag,
+// to find the subcommand, the flag expecting a value and the positional index.
+func (c *CLI) complete(ctx context.Context, words []string) ([]string, CompletionDirective) {
+	current := ""
+	if len(words) > 0 {
+		current = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+
+	flags, args, subcommands := c.flags, c.args, c.subcommands
+	positionals := 0
+
+	// The flag whose value is the current word. e.g --name <TAB>
+	var pending *Flag
+
+	lex := newLexer(words)
+	for {
+		tok, ok := lex.next()
+		if !ok {
+			break
+		}
+
+		pending = nil
+		switch tok.kind {
+		case tokenTerminator:
+			continue
+		case tokenPositional:
+			if strings.TrimSpace(tok.value) == "" {
+				continue
+			}
+
+			// A subcommand can only appear before any positional argument.
+			if !lex.terminated && positionals == 0 {
+				if cmd := findSubCommand(subcommands, tok.value); cmd != nil {
+					flags, args, subcommands = cmd.allFlags(), cmd.args, cmd.subcommands
+					continue
+				}
+			}
+			positionals++
+			continue
+		}
+
+		flag := valueFlag(flags, tok)
+		if flag == nil {
+			continue
+		}
+
+		if next, ok := lex.peek(); !ok {
+			pending = flag
+		} else if flag.acceptsValue(next) {
+			lex.takeValue()
+		}
+	}
+
+	if pending != nil {
+		return completeValue(ctx, pending, current, "")
+	}
+
+	if !lex.terminated && strings.HasPrefix(current, "-") {
+		// The inline value of a flag. e.g --name=jo
+		if tok := lexArg(current); tok.hasValue && tok.kind != tokenCluster {
+			if flag := findFlag(flags, tok.name); flag != nil && (flag.takesValue() || flag.isBool()) {
+				return completeValue(ctx, flag, tok.value, strings.TrimSuffix(tok.text, tok.value))
+			}
+			return nil, CompletionNoFile
+		}
+		return completeFlags(flags, current), CompletionNoFile
+	}
+
+	// Subcommands and then the positional argument at the cursor.
+	var candidates []string
+	if !lex.terminated && positionals == 0 {
+		for _, cmd := range subcommands {
+			if strings.HasPrefix(cmd.name, current) {
+				candidates = append(candidates, cmd.name+"\t"+cmd.description)
+			}
+		}
+	}
+
+	arg := argAt(args, positionals)
+	if arg == nil {
+		return candidates, CompletionNoFile
+	}
+
+	values, directive := completeValue(ctx, arg, current, "")
+	if len(candidates) > 0 && directive == CompletionDefault {
+		directive = CompletionNoFile
+	}
+	return append(candidates, values...), directive
+}
+
+// Returns the flag of a flag token that takes the next arg as its value,
+// or nil if the value is inline, the flag takes no value or is unknown.
+func valueFlag(flags []*Flag, tok token) *Flag {
+	if tok.hasValue {
+		return nil
+	}
+
+	if !isShortCluster(flags, tok) {
+		if flag := findFlag(flags, tok.name); flag != nil && flag.takesValue() {
+			return flag
+		}
+		return nil
+	}
+
+	// The first flag that takes a value in a cluster takes the rest of the
+	// cluster, or the next arg if it is the last flag. e.g -xvf archive.tar
+	for i, r := range tok.name {
+		flag := findShortFlag(flags, string(r))
+		if flag == nil {
+			return nil
+		}
+
+		if flag.takesValue() {
+			if i+len(string(r)) < len(tok.name) {
+				return nil
+			}
+			return flag
+		}
+	}
+	return nil
+}
+
+// Returns the long flags and negated flags that start with prefix.
+func completeFlags(flags []*Flag, prefix string) []string {
+	var candidates []string
+	for _, flag := range flags {
+		if name := "--" + flag.name; strings.HasPrefix(name, prefix) {
+			candidates = append(candidates, name+"\t"+flag.usage)
+		}
+
+		if name := "--no-" + flag.name; flag.negatable && strings.HasPrefix(name, prefix) {
+			candidates = append(candidates, name+"\tTurn off --"+flag.name)
+		}
+	}
+	return candidates
+}
+
+// Returns the candidates and directive of the value of a flag or argument
+// that starts with prefix. Each candidate is prepended with insert.
+// e.g --name= for --name=jo
+func completeValue(ctx context.Context, flag *Flag, prefix, insert string) ([]string, CompletionDirective) {
+	var values []string
+	switch {
+	case flag.completeFunc != nil:
+		values = flag.completeFunc(ctx, prefix)
+	case len(flag.enum) > 0:
+		values = flag.enum
+	case len(flag.extensions) > 0:
+		return flag.extensions, CompletionFilterExt
+	case flag.flagType == flagDirPath:
+		return nil, CompletionFilterDirs
+	case flag.flagType == flagFilePath || flag.positional:
+		return nil, CompletionDefault
+	case flag.isBool():
+		values = []string{"true", "false"}
+	default:
+		return nil, CompletionNoFile
+	}
+
+	var candidates []string
+	for _, value := range values {
+		if strings.HasPrefix(value, prefix) {
+			candidates = append(candidates, insert+value)
+		}
+	}
+	return candidates, CompletionNoFile
+}
+
+// Returns the positional argument at index n, the variadic tail beyond
+// the declared arguments or nil.
+func argAt(args []*Flag, n int) *Flag {
+	switch {
+	case n < len(args):
+		return args[n]
+	case len(args) > 0 && args[len(args)-1].variadic:
+		return args[len(args)-1]
+	}
+	return nil
+}
+
+// GenBashDynamicCompletion generates a bash completion script that calls
+// the hidden __complete command of the program for candidates, so that
+// flag and argument values can be completed at runtime. See Flag.Complete.
+func (c *CLI) GenBashDynamicCompletion(w io.Writer) {
+	binName := c.binName()
+
+	fmt.Fprintf(w, "#!/bin/bash\n")
+	fmt.Fprintf(w, "# Dynamic bash completion for %s\n", binName)
+	fmt.Fprintf(w, "# Generated by goflag\n\n")
+
+	fmt.Fprintf(w, "_%s_completion() {\n", binName)
+	fmt.Fprintf(w, "    local line=\"${COMP_LINE:0:COMP_POINT}\"\n")
+	fmt.Fprintf(w, "    local -a words lines\n")
+	fmt.Fprintf(w, "    read -ra words <<< \"$line\"\n")
+	fmt.Fprintf(w, "    if [[ -z \"$line\" || \"$line\" == *[[:space:]] ]]; then\n")
+	fmt.Fprintf(w, "        words+=(\"\")\n")
+	fmt.Fprintf(w, "    fi\n")
+	fmt.Fprintf(w, "    local cur=\"${words[${#words[@]}-1]}\"\n\n")
+
+	fmt.Fprintf(w, "    mapfile -t lines < <(\"${words[0]}\" %s \"${words[@]:1}\" 2>/dev/null)\n", completeCmdName)
+	fmt.Fprintf(w, "    (( ${#lines[@]} )) || return 0\n")
+	fmt.Fprintf(w, "    local last=\"${lines[${#lines[@]}-1]}\"\n")
+	fmt.Fprintf(w, "    [[ \"$last\" == :* ]] || return 0\n")
+	fmt.Fprintf(w, "    local directive=\"${last#:}\"\n")
+	fmt.Fprintf(w, "    unset 'lines[${#lines[@]}-1]'\n\n")
+
+	// Bash splits --name=value into separate words, so only the value is replaced.
+	fmt.Fprintf(w, "    local prefix=\"\"\n")
+	fmt.Fprintf(w, "    if [[ \"$cur\" == -*=* && \"$COMP_WORDBREAKS\" == *=* ]]; then\n")
+	fmt.Fprintf(w, "        prefix=\"${cur%%%%=*}=\"\n")
+	fmt.Fprintf(w, "        cur=\"${cur#*=}\"\n")
+	fmt.Fprintf(w, "    fi\n\n")
+
+	fmt.Fprintf(w, "    COMPREPLY=()\n")
+	fmt.Fprintf(w, "    local candidate\n")
+	fmt.Fprintf(w, "    if (( directive & %d )); then\n", CompletionFilterExt)
+	fmt.Fprintf(w, "        for candidate in \"${lines[@]}\"; do\n")
+	fmt.Fprintf(w, "            COMPREPLY+=( $(compgen -f -X \"!*.$candidate\" -- \"$cur\") )\n")
+	fmt.Fprintf(w, "        done\n")
+	fmt.Fprintf(w, "        COMPREPLY+=( $(compgen -d -- \"$cur\") )\n")
+	fmt.Fprintf(w, "        return 0\n")
+	fmt.Fprintf(w, "    fi\n\n")
+
+	fmt.Fprintf(w, "    if (( directive & %d )); then\n", CompletionFilterDirs)
+	fmt.Fprintf(w, "        COMPREPLY=( $(compgen -d -- \"$cur\") )\n")
+	fmt.Fprintf(w, "        return 0\n")
+	fmt.Fprintf(w, "    fi\n\n")
+
+	fmt.Fprintf(w, "    for candidate in \"${lines[@]}\"; do\n")
+	fmt.Fprintf(w, "        candidate=\"${candidate%%%%$'\\t'*}\"\n")
+	fmt.Fprintf(w, "        COMPREPLY+=( \"${candidate#\"$prefix\"}\" )\n")
+	fmt.Fprintf(w, "    done\n\n")
+
+	fmt.Fprintf(w, "    if (( ${#COMPREPLY[@]} == 0 && !(directive & %d) )); then\n", CompletionNoFile)
+	fmt.Fprintf(w, "        COMPREPLY=( $(compgen -f -- \"$cur\") )\n")
+	fmt.Fprintf(w, "    fi\n")
+	fmt.Fprintf(w, "    return 0\n")
+	fmt.Fprintf(w, "}\n\n")
+	fmt.Fprintf(w, "complete -F _%s_completion %s\n", binName, binName)
+}
+
+// GenZshDynamicCompletion generates a zsh completion script that calls
+// the hidden __complete command of the program for candidates, so that
+// flag and argument values can be completed at runtime. See Flag.Complete.
+func (c *CLI) GenZshDynamicCompletion(w io.Writer) {
+	binName := c.binName()
+
+	fmt.Fprintf(w, "#compdef %s\n", binName)
+	fmt.Fprintf(w, "# Dynamic zsh completion for %s\n", binName)
+	fmt.Fprintf(w, "# Generated by goflag\n\n")
+
+	fmt.Fprintf(w, "_%s() {\n", binName)
+	fmt.Fprintf(w, "    local -a lines candidates\n")
+	fmt.Fprintf(w, "    local directive line\n\n")
+
+	fmt.Fprintf(w, "    lines=(\"${(@f)$(\"${words[1]}\" %s \"${(@)words[2,CURRENT]}\" 2>/dev/null)}\")\n", completeCmdName)
+	fmt.Fprintf(w, "    [[ \"${lines[-1]}\" == :* ]] || return 1\n")
+	fmt.Fprintf(w, "    directive=\"${lines[-1]#:}\"\n")
+	fmt.Fprintf(w, "    lines=(\"${(@)lines[1,-2]}\")\n\n")
+
+	fmt.Fprintf(w, "    if (( directive & %d )); then\n", CompletionFilterExt)
+	fmt.Fprintf(w, "        _files -g \"*.(${(j:|:)lines})\"\n")
+	fmt.Fprintf(w, "        return\n")
+	fmt.Fprintf(w, "    fi\n\n")
+
+	fmt.Fprintf(w, "    if (( directive & %d )); then\n", CompletionFilterDirs)
+	fmt.Fprintf(w, "        _files -/\n")
+	fmt.Fprintf(w, "        return\n")
+	fmt.Fprintf(w, "    fi\n\n")
+
+	// _describe takes name:description, colons in the name are escaped.
+	fmt.Fprintf(w, "    for line in \"${lines[@]}\"; do\n")
+	fmt.Fprintf(w, "        if [[ \"$line\" == *$'\\t'* ]]; then\n")
+	fmt.Fprintf(w, "            candidates+=(\"${${line%%%%$'\\t'*}//:/\\\\:}:${line#*$'\\t'}\")\n")
+	fmt.Fprintf(w, "        else\n")
+	fmt.Fprintf(w, "            candidates+=(\"${line//:/\\\\:}\")\n")
+	fmt.Fprintf(w, "        fi\n")
+	fmt.Fprintf(w, "    done\n\n")
+
+	fmt.Fprintf(w, "    if (( ${#candidates} )); then\n")
+	fmt.Fprintf(w, "        _describe 'completions' candidates\n")
+	fmt.Fprintf(w, "    elif (( !(directive & %d) )); then\n", CompletionNoFile)
+	fmt.Fprintf(w, "        _files\n")
+	fmt.Fprintf(w, "    fi\n")
+	fmt.Fprintf(w, "}\n\n")
+
+	fmt.Fprintf(w, "if [[ \"${funcstack[1]}\" == \"_%s\" ]]; then\n", binName)
+	fmt.Fprintf(w, "    _%s \"$@\"\n", binName)
+	fmt.Fprintf(w, "else\n")
+	fmt.Fprintf(w, "    compdef _%s %s\n", binName, binName)
+	fmt.Fprintf(w, "fi\n")
+}
@@ -0,0 +1,87 @@
+package goflag
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestComplete(t *testing.T) {
+	var (
+		env, config, dir, name, target string
+		color                          = true
+		verbose                        int
+		files                          []string
+	)
+
+	cli := New()
+	cli.String("env", "e", &env, "Environment").
+		Complete(func(ctx context.Context, prefix string) []string {
+			return []string{"prod\tProduction", "preview", "dev"}
+		})
+	cli.FilePath("config", "c", &config, "Config file").CompleteFiles("yaml", "yml")
+	cli.Bool("color", "", &color, "Colorize output").Negatable()
+	cli.Count("verbose", "v", &verbose, "Verbosity")
+	cli.SubCommand("db", "Database commands", func() {}).
+		DirPath("dir", "d", &dir, "Data directory").
+		String("name", "n", &name, "Database name").
+		SubCommand("migrate", "Run migrations", func() {}).
+		Arg("target", &target, "Target version").
+		Complete(func(ctx context.Context, prefix string) []string {
+			return []string{"v1", "v2", "latest"}
+		}).
+		Arg("files", &files, "Migration files")
+
+	tests := []struct {
+		words      []string
+		candidates []string
+		directive  CompletionDirective
+	}{
+		{[]string{""}, []string{"completion\tGenerate shell completion scripts", "db\tDatabase commands"}, CompletionNoFile},
+		{[]string{"d"}, []string{"db\tDatabase commands"}, CompletionNoFile},
+		{[]string{"--co"}, []string{"--config\tConfig file", "--color\tColorize output"}, CompletionNoFile},
+		{[]string{"--no"}, []string{"--no-color\tTurn off --color"}, CompletionNoFile},
+		{[]string{"--env", "p"}, []string{"prod\tProduction", "preview"}, CompletionNoFile},
+		{[]string{"-ve", ""}, []string{"prod\tProduction", "preview", "dev"}, CompletionNoFile},
+		{[]string{"--env=pr"}, []string{"--env=prod\tProduction", "--env=preview"}, CompletionNoFile},
+		{[]string{"--color="}, []string{"--color=true", "--color=false"}, CompletionNoFile},
+		{[]string{"-c", ""}, []string{"yaml", "yml"}, CompletionFilterExt},
+		{[]string{"--env", "dev", "db", "--dir", ""}, nil, CompletionFilterDirs},
+		{[]string{"db", "-n", "x", ""}, []string{"migrate\tRun migrations"}, CompletionNoFile},
+		{[]string{"db", "--name", ""}, nil, CompletionNoFile},
+		{[]string{"db", "migrate", "--d"}, []string{"--dir\tData directory"}, CompletionNoFile},
+		{[]string{"db", "migrate", "-d", "data", "l"}, []string{"latest"}, CompletionNoFile},
+		{[]string{"db", "migrate", "v1", ""}, nil, CompletionDefault},
+		{[]string{"db", "migrate", "--", "-"}, nil, CompletionNoFile},
+		{[]string{"db", "migrate", "v1", "--", "-"}, nil, CompletionDefault},
+		{[]string{"--", ""}, nil, CompletionNoFile},
+	}
+
+	for _, test := range tests {
+		candidates, directive := cli.complete(context.Background(), test.words)
+		if !slices.Equal(candidates, test.candidates) || directive != test.directive {
+			t.Errorf("%q: expected %q and directive %d, but got %q and %d",
+				test.words, test.candidates, test.directive, candidates, directive)
+		}
+	}
+
+	// The hidden __complete command prints the candidates and the directive.
+	var out bytes.Buffer
+	cli.SetOutput(&out)
+	if err := cli.Run(context.Background(), []string{"app", "__complete", "--env", "d"}); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if out.String() != "dev\n:1\n" {
+		t.Errorf("Expected dev and directive 1, but got %q", out.String())
+	}
+
+	// It is not shown in the help.
+	out.Reset()
+	cli.PrintUsage(&out)
+	if strings.Contains(out.String(), "__complete") {
+		t.Errorf("Expected __complete to be hidden, got:\n%s", out.String())
+	}
+}
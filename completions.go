@@ -3,126 +3,171 @@ package goflag
 import (
 	"fmt"
 	"io"
-	"os"
-	"path/filepath"
+	"slices"
 	"strings"
+	"unicode/utf8"
 )
 
 // GenBashCompletion generates a bash completion script and writes it to w.
 // The completion script provides intelligent tab completion for commands, subcommands,
 // and flags. Only long-form flags (--flag) are shown in completions to reduce clutter;
 // short flags can still be used but won't appear in completion suggestions.
+//
+// Nested subcommands are completed by tracking the subcommand path of the
+// words before the cursor, e.g /db/migrate.
 func (c *CLI) GenBashCompletion(w io.Writer) {
-	binName := filepath.Base(os.Args[0])
+	binName := c.binName()
 
 	fmt.Fprintf(w, "#!/bin/bash\n")
 	fmt.Fprintf(w, "# Bash completion for %s\n", binName)
 	fmt.Fprintf(w, "# Generated by goflag\n\n")
 
 	fmt.Fprintf(w, "_%s_completion() {\n", binName)
-	fmt.Fprintf(w, "    local cur prev subcommands\n")
+	fmt.Fprintf(w, "    local cur prev word subcommands flags\n")
 	fmt.Fprintf(w, "    cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
 	fmt.Fprintf(w, "    prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n\n")
 
-	// Collect global flags (long form only for cleaner completion)
-	var globalFlags []string
-	for _, f := range c.flags {
-		globalFlags = append(globalFlags, "--"+f.name)
-	}
+	// Everything after the -- terminator is positional, so fall back to file completion.
+	fmt.Fprintf(w, "    # Stop completing flags after the -- terminator\n")
+	fmt.Fprintf(w, "    local i\n")
+	fmt.Fprintf(w, "    for (( i=1; i < COMP_CWORD; i++ )); do\n")
+	fmt.Fprintf(w, "        if [[ \"${COMP_WORDS[i]}\" == \"--\" ]]; then\n")
+	fmt.Fprintf(w, "            COMPREPLY=( $(compgen -f -- \"$cur\") )\n")
+	fmt.Fprintf(w, "            return 0\n")
+	fmt.Fprintf(w, "        fi\n")
+	fmt.Fprintf(w, "    done\n\n")
 
-	// Collect subcommand names
-	var subCmdNames []string
-	for _, cmd := range c.subcommands {
-		subCmdNames = append(subCmdNames, cmd.name)
+	// Collect the paths of all subcommands in the tree.
+	var paths []string
+	walkSubCommands(c.subcommands, func(cmd *subcommand) {
+		paths = append(paths, bashCmdPath(cmd))
+	})
+
+	// Find the subcommand path of the words before the cursor.
+	fmt.Fprintf(w, "    # Find the subcommand context. e.g /db/migrate\n")
+	fmt.Fprintf(w, "    local cmd_path=\"\"\n")
+	if len(paths) > 0 {
+		fmt.Fprintf(w, "    for word in \"${COMP_WORDS[@]:1:COMP_CWORD-1}\"; do\n")
+		fmt.Fprintf(w, "        case \"$cmd_path/$word\" in\n")
+		fmt.Fprintf(w, "            %s)\n", strings.Join(paths, "|"))
+		fmt.Fprintf(w, "                cmd_path=\"$cmd_path/$word\"\n")
+		fmt.Fprintf(w, "                ;;\n")
+		fmt.Fprintf(w, "        esac\n")
+		fmt.Fprintf(w, "    done\n")
 	}
+	fmt.Fprintf(w, "\n")
+
+	// Handle each context: flag arguments first, then subcommands and flags.
+	fmt.Fprintf(w, "    case \"$cmd_path\" in\n")
+	writeBashContext(w, `""`, c.flags, c.subcommands, c.constraints)
+	walkSubCommands(c.subcommands, func(cmd *subcommand) {
+		writeBashContext(w, bashCmdPath(cmd), cmd.allFlags(), cmd.subcommands, cmd.allConstraints())
+	})
+	fmt.Fprintf(w, "    esac\n\n")
+
+	fmt.Fprintf(w, "    COMPREPLY=( $(compgen -W \"$subcommands $flags\" -- \"$cur\") )\n")
+	fmt.Fprintf(w, "    return 0\n")
+	fmt.Fprintf(w, "}\n\n")
+	fmt.Fprintf(w, "complete -F _%s_completion %s\n", binName, binName)
+}
 
-	fmt.Fprintf(w, "    subcommands=\"%s\"\n", strings.Join(subCmdNames, " "))
-	fmt.Fprintf(w, "    global_flags=\"%s\"\n\n", strings.Join(globalFlags, " "))
+// Write the bash case branch for a subcommand context.
+// Flags that need arguments are handled first, include both long and short forms for matching.
+// Flags that are mutually exclusive with a flag already on the command line are not suggested.
+func writeBashContext(w io.Writer, pattern string, flags []*Flag, subcommands []*subcommand, constraints []constraint) {
+	fmt.Fprintf(w, "        %s)\n", pattern)
 
-	// Handle flag arguments (Global) - include both long and short forms for matching
-	fmt.Fprintf(w, "    # Handle flags that need arguments\n")
-	fmt.Fprintf(w, "    case \"$prev\" in\n")
-	for _, f := range c.flags {
-		if f.flagType != flagBool {
-			flags := []string{"--" + f.name}
+	fmt.Fprintf(w, "            case \"$prev\" in\n")
+	for _, f := range flags {
+		if f.takesValue() {
+			names := []string{"--" + f.name}
 			if f.shortName != "" {
-				flags = append(flags, "-"+f.shortName)
+				names = append(names, "-"+f.shortName)
 			}
-			fmt.Fprintf(w, "        %s)\n", strings.Join(flags, "|"))
-			// Suggest files or directories if the flag type matches
-			switch f.flagType {
-			case flagDirPath:
-				fmt.Fprintf(w, "            COMPREPLY=( $(compgen -d -- \"$cur\") )\n")
-			case flagFilePath:
-				fmt.Fprintf(w, "            COMPREPLY=( $(compgen -f -- \"$cur\") )\n")
+			fmt.Fprintf(w, "                %s)\n", strings.Join(names, "|"))
+			// Suggest the enumerated values, or files or directories if the flag type matches
+			switch {
+			case len(f.enum) > 0:
+				fmt.Fprintf(w, "                    COMPREPLY=( $(compgen -W \"%s\" -- \"$cur\") )\n", bashWords(f.enum))
+			case f.flagType == flagDirPath:
+				fmt.Fprintf(w, "                    COMPREPLY=( $(compgen -d -- \"$cur\") )\n")
+			case f.flagType == flagFilePath:
+				fmt.Fprintf(w, "                    COMPREPLY=( $(compgen -f -- \"$cur\") )\n")
 			}
-			fmt.Fprintf(w, "            return 0\n")
-			fmt.Fprintf(w, "            ;;\n")
+			fmt.Fprintf(w, "                    return 0\n")
+			fmt.Fprintf(w, "                    ;;\n")
+		}
+	}
+	fmt.Fprintf(w, "            esac\n")
+
+	// Only show long-form flags in completions
+	var longFlags []string
+	for _, f := range flags {
+		longFlags = append(longFlags, "--"+f.name)
+		if f.negatable {
+			longFlags = append(longFlags, "--no-"+f.name)
 		}
 	}
-	fmt.Fprintf(w, "    esac\n\n")
 
-	// Check for subcommand context
-	fmt.Fprintf(w, "    # Check for subcommand context\n")
-	fmt.Fprintf(w, "    local cmd_context=\"\"\n")
-	fmt.Fprintf(w, "    for word in \"${COMP_WORDS[@]}\"; do\n")
-	fmt.Fprintf(w, "        for cmd in $subcommands; do\n")
-	fmt.Fprintf(w, "            if [[ \"$word\" == \"$cmd\" ]]; then\n")
-	fmt.Fprintf(w, "                cmd_context=\"$cmd\"\n")
-	fmt.Fprintf(w, "                break\n")
-	fmt.Fprintf(w, "            fi\n")
-	fmt.Fprintf(w, "        done\n")
-	fmt.Fprintf(w, "        if [[ -n \"$cmd_context\" ]]; then break; fi\n")
-	fmt.Fprintf(w, "    done\n\n")
+	var names []string
+	for _, cmd := range subcommands {
+		names = append(names, cmd.name)
+	}
 
-	// If no subcommand is active, suggest subcommands and global flags
-	fmt.Fprintf(w, "    if [[ -z \"$cmd_context\" ]]; then\n")
-	fmt.Fprintf(w, "        COMPREPLY=( $(compgen -W \"$subcommands $global_flags\" -- \"$cur\") )\n")
-	fmt.Fprintf(w, "        return 0\n")
-	fmt.Fprintf(w, "    fi\n\n")
-
-	// Handle subcommand-specific completions
-	fmt.Fprintf(w, "    case \"$cmd_context\" in\n")
-	for _, cmd := range c.subcommands {
-		fmt.Fprintf(w, "        %s)\n", cmd.name)
-
-		// Handle subcommand flags that need arguments - include both long and short forms
-		fmt.Fprintf(w, "            case \"$prev\" in\n")
-		for _, f := range cmd.flags {
-			if f.flagType != flagBool {
-				flags := []string{"--" + f.name}
-				if f.shortName != "" {
-					flags = append(flags, "-"+f.shortName)
-				}
-				fmt.Fprintf(w, "                %s)\n", strings.Join(flags, "|"))
-				switch f.flagType {
-				case flagDirPath:
-					fmt.Fprintf(w, "                    COMPREPLY=( $(compgen -d -- \"$cur\") )\n")
-				case flagFilePath:
-					fmt.Fprintf(w, "                    COMPREPLY=( $(compgen -f -- \"$cur\") )\n")
+	fmt.Fprintf(w, "            subcommands=\"%s\"\n", strings.Join(names, " "))
+	fmt.Fprintf(w, "            flags=\"%s\"\n", strings.Join(longFlags, " "))
+
+	excluded := exclusiveFlags(constraints)
+	if len(excluded) > 0 {
+		fmt.Fprintf(w, "            flags=\" $flags \"\n")
+		fmt.Fprintf(w, "            for word in \"${COMP_WORDS[@]:1:COMP_CWORD-1}\"; do\n")
+		fmt.Fprintf(w, "                case \"$word\" in\n")
+		for _, f := range flags {
+			others, ok := excluded[f.name]
+			if !ok {
+				continue
+			}
+
+			names := []string{"--" + f.name}
+			if f.shortName != "" {
+				names = append(names, "-"+f.shortName)
+			}
+			if f.negatable {
+				names = append(names, "--no-"+f.name)
+			}
+			fmt.Fprintf(w, "                    %s)\n", strings.Join(names, "|"))
+			for _, other := range others {
+				fmt.Fprintf(w, "                        flags=\"${flags// --%s / }\"\n", other)
+				if o := findFlag(flags, other); o != nil && o.negatable {
+					fmt.Fprintf(w, "                        flags=\"${flags// --no-%s / }\"\n", other)
 				}
-				fmt.Fprintf(w, "                    return 0\n")
-				fmt.Fprintf(w, "                    ;;\n")
 			}
+			fmt.Fprintf(w, "                        ;;\n")
 		}
-		fmt.Fprintf(w, "            esac\n\n")
+		fmt.Fprintf(w, "                esac\n")
+		fmt.Fprintf(w, "            done\n")
+	}
+	fmt.Fprintf(w, "            ;;\n")
+}
 
-		// Only show long-form flags in completions
-		var subFlags []string
-		for _, f := range cmd.flags {
-			subFlags = append(subFlags, "--"+f.name)
-		}
+// Returns the words of compgen -W, escaped for bash double quotes.
+// compgen splits the words on whitespace.
+func bashWords(words []string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`")
+	return replacer.Replace(strings.Join(words, " "))
+}
 
-		fmt.Fprintf(w, "            local flags=\"%s\"\n", strings.Join(subFlags, " "))
-		fmt.Fprintf(w, "            COMPREPLY=( $(compgen -W \"$flags\" -- \"$cur\") )\n")
-		fmt.Fprintf(w, "            return 0\n")
-		fmt.Fprintf(w, "            ;;\n")
-	}
+// Returns the bash case pattern for a subcommand path. e.g /db/migrate
+func bashCmdPath(cmd *subcommand) string {
+	return "/" + strings.ReplaceAll(cmd.Path(), " ", "/")
+}
 
-	fmt.Fprintf(w, "    esac\n\n")
-	fmt.Fprintf(w, "    return 0\n")
-	fmt.Fprintf(w, "}\n\n")
-	fmt.Fprintf(w, "complete -F _%s_completion %s\n", binName, binName)
+// Call fn for each subcommand in the tree, parents before children.
+func walkSubCommands(cmds []*subcommand, fn func(cmd *subcommand)) {
+	for _, cmd := range cmds {
+		fn(cmd)
+		walkSubCommands(cmd.subcommands, fn)
+	}
 }
 
 // GenZshCompletion generates a zsh completion script and writes it to w.
@@ -132,49 +177,49 @@ func (c *CLI) GenBashCompletion(w io.Writer) {
 //
 // The completion will show both subcommands and global flags when pressing tab at the
 // command prompt, making it easy to discover both options.
-// GenZshCompletion generates a zsh completion script and writes it to w.
+// Each subcommand with nested subcommands or flags gets its own completion function.
 func (c *CLI) GenZshCompletion(w io.Writer) {
-	binName := filepath.Base(os.Args[0])
+	binName := c.binName()
 
 	fmt.Fprintf(w, "#compdef %s\n", binName)
 	fmt.Fprintf(w, "# Generated by goflag\n\n")
 
-	fmt.Fprintf(w, "_%s() {\n", binName)
-	fmt.Fprintf(w, "    local -a global_opts\n")
+	writeZshFunction(w, binName, "_"+binName, c.flags, c.subcommands, c.constraints)
+	walkSubCommands(c.subcommands, func(cmd *subcommand) {
+		writeZshFunction(w, binName, zshFuncName(binName, cmd), cmd.allFlags(), cmd.subcommands, cmd.allConstraints())
+	})
+
+	fmt.Fprintf(w, "_%s \"$@\"\n", binName)
+}
+
+// Write a zsh completion function for a command with the given flags and subcommands.
+// Mutually exclusive flags are excluded once one of them is on the command line.
+func writeZshFunction(w io.Writer, binName, funcName string, flags []*Flag, subcommands []*subcommand, constraints []constraint) {
+	fmt.Fprintf(w, "%s() {\n", funcName)
+	fmt.Fprintf(w, "    local -a opts\n")
 	fmt.Fprintf(w, "    local -a subcommands\n")
 	fmt.Fprintf(w, "    local context state line\n")
 	fmt.Fprintf(w, "    local ret=1\n\n")
 
-	// Define Global Flags
-	fmt.Fprintf(w, "    global_opts=(\n")
-	for _, f := range c.flags {
-		// Escape brackets in usage text as they are special in zsh _arguments
-		desc := strings.ReplaceAll(f.usage, "]", "\\]")
-		desc = strings.ReplaceAll(desc, "'", "'\\''")
-
-		// Determine argument specification
-		argSpec := ""
-		switch f.flagType {
-		case flagBool:
-			argSpec = ""
-		case flagDirPath:
-			argSpec = ":dir:_files -/"
-		case flagFilePath:
-			argSpec = ":file:_files"
-		default:
-			argSpec = ":value:"
+	// Define Flags
+	excluded := exclusiveFlags(constraints)
+	fmt.Fprintf(w, "    opts=(\n")
+	for _, f := range flags {
+		if !f.negatable {
+			fmt.Fprintf(w, "        %s\n", zshFlagSpec(f, excluded[f.name]))
+			continue
 		}
 
-		// Display only long flags
-		fmt.Fprintf(w, "        '--%s[%s]%s'\n", f.name, desc, argSpec)
-
+		// --name and --no-name exclude each other.
+		fmt.Fprintf(w, "        %s\n", zshFlagSpec(f, append(slices.Clone(excluded[f.name]), "no-"+f.name)))
+		fmt.Fprintf(w, "        %s\n", zshNegatedFlagSpec(f, excluded[f.name]))
 	}
 	fmt.Fprintf(w, "    )\n\n")
 
 	// Define Subcommands
-	if len(c.subcommands) > 0 {
+	if len(subcommands) > 0 {
 		fmt.Fprintf(w, "    subcommands=(\n")
-		for _, cmd := range c.subcommands {
+		for _, cmd := range subcommands {
 			// Escape descriptions for Zsh string
 			desc := strings.ReplaceAll(cmd.description, "'", "'\\''")
 			// Zsh _arguments (( )) syntax expects 'name:description'
@@ -184,301 +229,474 @@ func (c *CLI) GenZshCompletion(w io.Writer) {
 	}
 
 	// Main _arguments call
-	// We pass global_opts as normal arguments.
+	// -S stops option completion after a -- terminator.
+	// We pass opts as normal arguments.
 	// We pass subcommands specifically to the first positional argument.
 	// Zsh automatically handles the "Flag OR Subcommand" logic here.
-	fmt.Fprintf(w, "    _arguments -C \\\n")
-	fmt.Fprintf(w, "        \"${global_opts[@]}\" \\\n")
-	if len(c.subcommands) > 0 {
+	fmt.Fprintf(w, "    _arguments -C -S \\\n")
+	fmt.Fprintf(w, "        \"${opts[@]}\" \\\n")
+	if len(subcommands) > 0 {
 		// The (( )) syntax tells _arguments to use the subcommands array for completion items
 		fmt.Fprintf(w, "        '1:command:((${subcommands}))' \\\n")
 		fmt.Fprintf(w, "        '*::arg:->args' \\\n")
 	}
 	fmt.Fprintf(w, "        && ret=0\n\n")
 
-	// State machine for subcommand-specific flags
-	if len(c.subcommands) > 0 {
+	// State machine for nested subcommands
+	if len(subcommands) > 0 {
 		fmt.Fprintf(w, "    case $state in\n")
 		fmt.Fprintf(w, "        args)\n")
 		fmt.Fprintf(w, "            case $line[1] in\n")
-
-		for _, cmd := range c.subcommands {
+		for _, cmd := range subcommands {
 			fmt.Fprintf(w, "                %s)\n", cmd.name)
-			fmt.Fprintf(w, "                    _arguments -C \\\n")
-			for _, f := range cmd.flags {
-				desc := strings.ReplaceAll(f.usage, "]", "\\]")
-				desc = strings.ReplaceAll(desc, "'", "'\\''")
-
-				argSpec := ""
-				switch f.flagType {
-				case flagBool:
-					argSpec = ""
-				case flagDirPath:
-					argSpec = ":dir:_files -/"
-				case flagFilePath:
-					argSpec = ":file:_files"
-				default:
-					argSpec = ":value:"
-				}
-
-				// Show only long flags
-				fmt.Fprintf(w, "                        '--%s[%s]%s' \\\n", f.name, desc, argSpec)
-
-			}
-			fmt.Fprintf(w, "                        && ret=0\n")
+			fmt.Fprintf(w, "                    %s && ret=0\n", zshFuncName(binName, cmd))
 			fmt.Fprintf(w, "                    ;;\n")
 		}
-
 		fmt.Fprintf(w, "            esac\n")
 		fmt.Fprintf(w, "            ;;\n")
-		fmt.Fprintf(w, "    esac\n")
+		fmt.Fprintf(w, "    esac\n\n")
 	}
 
 	fmt.Fprintf(w, "    return ret\n")
 	fmt.Fprintf(w, "}\n\n")
-	fmt.Fprintf(w, "_%s \"$@\"\n", binName)
 }
 
-// InstallCompletion installs shell completion scripts for the CLI application.
-// This function automatically detects the user's shell (bash or zsh) and installs
-// the appropriate completion script to the user's home directory.
-//
-// For bash, the completion script is installed to ~/.bash_completion.d/<binName>
-// and a source line is added to ~/.bashrc if not already present.
-//
-// For zsh, the completion script is installed to ~/.zsh/completion/_<binName>
-// and the necessary fpath and compinit lines are added to ~/.zshrc if not already present.
-//
-// Parameters:
-//   - shell: The shell to install completions for ("bash" or "zsh")
-//
-// Returns an error if installation fails.
-//
-// Example:
-//
-//	cli := goflag.NewCLI()
-//	if err := cli.InstallCompletion("bash"); err != nil {
-//	    fmt.Fprintf(os.Stderr, "Failed to install completion: %v\n", err)
-//	}
-func (c *CLI) InstallCompletion(shell string) error {
-	binName := filepath.Base(os.Args[0])
-
-	var generateFunc func(io.Writer)
-	switch shell {
-	case "bash":
-		generateFunc = c.GenBashCompletion
-	case "zsh":
-		generateFunc = c.GenZshCompletion
+// Returns the zsh _arguments spec of a flag. Only long flags are displayed.
+// excluded are the names of the flags that can not be used with it.
+func zshFlagSpec(f *Flag, excluded []string) string {
+	// Escape brackets in usage text as they are special in zsh _arguments
+	desc := strings.ReplaceAll(f.usage, "]", "\\]")
+	desc = strings.ReplaceAll(desc, "'", "'\\''")
+
+	// Determine argument specification
+	argSpec := ""
+	switch {
+	case !f.takesValue():
+		argSpec = ""
+	case len(f.enum) > 0:
+		argSpec = ":" + f.name + ":" + zshValuesAction(f.name, f.enum)
+	case f.flagType == flagDirPath:
+		argSpec = ":dir:_files -/"
+	case f.flagType == flagFilePath:
+		argSpec = ":file:_files"
+	case f.flagType == flagCustom || f.flagType == flagRegistered:
+		argSpec = ":" + f.Value().Type() + ":"
 	default:
-		return fmt.Errorf("unsupported shell: %s (supported: bash, zsh)", shell)
+		argSpec = ":value:"
+	}
+	exclusion := ""
+	if len(excluded) > 0 {
+		exclusion = "(--" + strings.Join(excluded, " --") + ")"
 	}
 
-	return installCompletion(shell, binName, generateFunc)
+	// Count flags and slice flags in append mode may be repeated.
+	if f.repeatable() {
+		exclusion += "*"
+	}
+	return fmt.Sprintf("'%s--%s[%s]%s'", exclusion, f.name, desc, argSpec)
 }
 
-// UninstallCompletion removes shell completion scripts for the CLI application.
-// This function removes the completion script from the user's home directory and
-// cleans up any references to it in shell configuration files.
-//
-// For bash, the completion script is removed from ~/.bash_completion.d/<binName>
-// and the source line is removed from ~/.bashrc.
-//
-// For zsh, the completion script is removed from ~/.zsh/completion/_<binName>.
-// Note that fpath and compinit lines remain in ~/.zshrc as they may be used by
-// other completions.
-//
-// Parameters:
-//   - shell: The shell to uninstall completions for ("bash" or "zsh")
-//
-// Returns an error if uninstallation fails.
-//
-// Example:
-//
-//	cli := goflag.NewCLI()
-//	if err := cli.UninstallCompletion("bash"); err != nil {
-//	    fmt.Fprintf(os.Stderr, "Failed to uninstall completion: %v\n", err)
-//	}
-func (c *CLI) UninstallCompletion(shell string) error {
-	binName := filepath.Base(os.Args[0])
-	return uninstallCompletion(shell, binName)
+// Returns the _values action of the enumerated values of a flag.
+// e.g _values "shell" "bash" "zsh"
+// Characters special to _values are escaped with a backslash.
+func zshValuesAction(name string, values []string) string {
+	quote := func(s string) string {
+		s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`", "[", `\\[`, "]", `\\]`, ":", `\\:`).Replace(s)
+		return `"` + strings.ReplaceAll(s, "'", `'\''`) + `"`
+	}
+
+	words := []string{"_values", quote(name)}
+	for _, value := range values {
+		words = append(words, quote(value))
+	}
+	return strings.Join(words, " ")
 }
 
-// installCompletion installs the completion script to the appropriate location
-// installCompletion installs the completion script to the appropriate location
-func installCompletion(shell, binName string, generateFunc func(io.Writer)) error {
-	var installPath string
-	var scriptContent strings.Builder
+// Returns the zsh _arguments spec of the --no-<name> form of a negatable flag.
+func zshNegatedFlagSpec(f *Flag, excluded []string) string {
+	exclusion := "(--" + strings.Join(append([]string{f.name}, excluded...), " --") + ")"
+	return fmt.Sprintf("'%s--no-%s[Turn off --%s]'", exclusion, f.name, f.name)
+}
 
-	generateFunc(&scriptContent)
+// Returns the name of the zsh completion function of a subcommand. e.g _myapp_db_migrate
+func zshFuncName(binName string, cmd *subcommand) string {
+	name := "_" + binName + "_" + strings.ReplaceAll(cmd.Path(), " ", "_")
+	return strings.ReplaceAll(name, "-", "_")
+}
 
-	switch shell {
-	case "bash":
-		// Try user-specific location first
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			return fmt.Errorf("failed to get home directory: %w", err)
-		}
+// GenFishCompletion generates a fish completion script and writes it to w.
+// The script has a complete rule for each subcommand and flag, scoped to the
+// subcommand path of the words before the cursor, e.g /db/migrate.
+// Descriptions are taken from the usage of the flags and subcommands.
+// Flags of type FilePath and DirPath complete files and directories.
+// Positional arguments complete their Enum values or files.
+func (c *CLI) GenFishCompletion(w io.Writer) {
+	binName := c.binName()
+	prefix := "__" + strings.NewReplacer("-", "_", ".", "_").Replace(binName)
+
+	fmt.Fprintf(w, "# Fish completion for %s\n", binName)
+	fmt.Fprintf(w, "# Generated by goflag\n\n")
 
-		// Create bash completion directory
-		completionDir := filepath.Join(homeDir, ".bash_completion.d")
-		if err := os.MkdirAll(completionDir, 0755); err != nil {
-			return fmt.Errorf("failed to create completion directory: %w", err)
-		}
+	// Collect the paths of all subcommands in the tree.
+	var paths []string
+	walkSubCommands(c.subcommands, func(cmd *subcommand) {
+		paths = append(paths, bashCmdPath(cmd))
+	})
+
+	// Find the subcommand path of the words before the cursor.
+	fmt.Fprintf(w, "function %s_cmd_path\n", prefix)
+	fmt.Fprintf(w, "    set -l path \"\"\n")
+	if len(paths) > 0 {
+		fmt.Fprintf(w, "    set -l words (commandline -opc)\n")
+		fmt.Fprintf(w, "    set -e words[1]\n")
+		fmt.Fprintf(w, "    for word in $words\n")
+		fmt.Fprintf(w, "        test \"$word\" = \"--\"; and break\n")
+		fmt.Fprintf(w, "        switch \"$path/$word\"\n")
+		fmt.Fprintf(w, "            case %s\n", strings.Join(paths, " "))
+		fmt.Fprintf(w, "                set path \"$path/$word\"\n")
+		fmt.Fprintf(w, "        end\n")
+		fmt.Fprintf(w, "    end\n")
+	}
+	fmt.Fprintf(w, "    echo \"$path\"\n")
+	fmt.Fprintf(w, "end\n\n")
 
-		installPath = filepath.Join(completionDir, binName)
+	fmt.Fprintf(w, "function %s_using_path\n", prefix)
+	fmt.Fprintf(w, "    set -l path (%s_cmd_path)\n", prefix)
+	fmt.Fprintf(w, "    test \"$path\" = \"$argv[1]\"\n")
+	fmt.Fprintf(w, "end\n\n")
 
-		// Write the completion script
-		if err := os.WriteFile(installPath, []byte(scriptContent.String()), 0644); err != nil {
-			return fmt.Errorf("failed to write completion script: %w", err)
-		}
+	// Files are only completed for file and directory flags and positional arguments.
+	fmt.Fprintf(w, "complete -c %s -f\n", binName)
 
-		// Add source line to .bashrc if not already present
-		bashrcPath := filepath.Join(homeDir, ".bashrc")
-		sourceLine := fmt.Sprintf("source %s", installPath)
+	writeFishContext(w, binName, prefix, "", c.flags, c.args, c.subcommands)
+	walkSubCommands(c.subcommands, func(cmd *subcommand) {
+		writeFishContext(w, binName, prefix, bashCmdPath(cmd), cmd.allFlags(), cmd.args, cmd.subcommands)
+	})
+}
 
-		// Check for the specific source line
-		if err := addSourceLineIfMissing(bashrcPath, sourceLine, sourceLine); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: Could not update .bashrc: %v\n", err)
-			fmt.Fprintf(os.Stderr, "Add this line to your .bashrc manually:\n  %s\n", sourceLine)
-		}
+// Write the fish complete rules of the subcommands, flags and positional
+// arguments of a subcommand path.
+func writeFishContext(w io.Writer, binName, prefix, path string, flags, args []*Flag, subcommands []*subcommand) {
+	condition := fishQuote(fmt.Sprintf("%s_using_path \"%s\"", prefix, path))
 
-		fmt.Printf("Bash completion installed to: %s\n", installPath)
-		fmt.Println("Run 'source ~/.bashrc' or restart your terminal to enable completions")
+	fmt.Fprintln(w)
+	for _, cmd := range subcommands {
+		fmt.Fprintf(w, "complete -c %s -n %s -a %s -d %s\n", binName, condition, fishQuote(cmd.name), fishQuote(cmd.description))
+	}
 
-	case "zsh":
-		homeDir, err := os.UserHomeDir()
-		if err != nil {
-			return fmt.Errorf("failed to get home directory: %w", err)
+	for _, f := range flags {
+		fmt.Fprintf(w, "complete -c %s -n %s%s -d %s\n", binName, condition, fishFlagSpec(f), fishQuote(f.usage))
+		if f.negatable {
+			fmt.Fprintf(w, "complete -c %s -n %s -l %s -d %s\n", binName, condition, fishQuote("no-"+f.name), fishQuote("Turn off --"+f.name))
 		}
+	}
 
-		// Create zsh completion directory
-		completionDir := filepath.Join(homeDir, ".zsh", "completion")
-		if err := os.MkdirAll(completionDir, 0755); err != nil {
-			return fmt.Errorf("failed to create completion directory: %w", err)
+	// Arguments without Enum values complete files, like the dynamic completion.
+	files := false
+	for _, arg := range args {
+		switch {
+		case len(arg.enum) > 0:
+			fmt.Fprintf(w, "complete -c %s -n %s -a %s -d %s\n", binName, condition, fishQuote(strings.Join(arg.enum, " ")), fishQuote(arg.usage))
+		case !files:
+			fmt.Fprintf(w, "complete -c %s -n %s -F\n", binName, condition)
+			files = true
 		}
+	}
+}
 
-		installPath = filepath.Join(completionDir, "_"+binName)
-
-		// Write the completion script
-		if err := os.WriteFile(installPath, []byte(scriptContent.String()), 0644); err != nil {
-			return fmt.Errorf("failed to write completion script: %w", err)
+// Returns the options of a fish complete rule for a flag. e.g -l name -s n -x
+func fishFlagSpec(f *Flag) string {
+	spec := " -l " + fishQuote(f.name)
+	if f.shortName != "" {
+		if utf8.RuneCountInString(f.shortName) == 1 {
+			spec += " -s " + fishQuote(f.shortName)
+		} else {
+			spec += " -o " + fishQuote(f.shortName)
 		}
+	}
 
-		// Add fpath and compinit to .zshrc if not already present
-		zshrcPath := filepath.Join(homeDir, ".zshrc")
+	switch {
+	case !f.takesValue():
+	case len(f.enum) > 0:
+		spec += " -x -a " + fishQuote(strings.Join(f.enum, " "))
+	case f.flagType == flagFilePath:
+		spec += " -r -F"
+	case f.flagType == flagDirPath:
+		spec += " -x -a '(__fish_complete_directories)'"
+	default:
+		spec += " -x"
+	}
+	return spec
+}
 
-		// FIX: Use the literal string "~/.zsh/completion" for the check,
-		// NOT the absolute path variable 'completionDir'
-		fpathStr := "~/.zsh/completion"
-		fpathLine := fmt.Sprintf("fpath=(%s $fpath)", fpathStr)
-		compinitLine := "autoload -Uz compinit && compinit"
+// Quote a string for fish. Only backslashes and single quotes are special
+// in single-quoted fish strings.
+func fishQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
 
-		if err := addSourceLineIfMissing(zshrcPath, fpathLine, fpathStr); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: Could not update .zshrc: %v\n", err)
-		}
-		if err := addSourceLineIfMissing(zshrcPath, compinitLine, "compinit"); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: Could not update .zshrc: %v\n", err)
-		}
+// GenPowerShellCompletion generates a PowerShell completion script and writes it to w.
+// The script registers a native argument completer that suggests the subcommands
+// and long flags of the subcommand path of the words before the cursor, e.g /db/migrate.
+// Descriptions are shown as tooltips.
+//
+// Flags of type DirPath complete directories. The values of other flags fall
+// back to the default path completion of PowerShell.
+func (c *CLI) GenPowerShellCompletion(w io.Writer) {
+	binName := c.binName()
 
-		fmt.Printf("Zsh completion installed to: %s\n", installPath)
-		fmt.Println("Run 'source ~/.zshrc' or restart your terminal to enable completions")
+	fmt.Fprintf(w, "# PowerShell completion for %s\n", binName)
+	fmt.Fprintf(w, "# Generated by goflag\n\n")
 
-	default:
-		return fmt.Errorf("unsupported shell: %s", shell)
+	fmt.Fprintf(w, "Register-ArgumentCompleter -Native -CommandName %s -ScriptBlock {\n", psQuote(binName))
+	fmt.Fprintf(w, "    param($wordToComplete, $commandAst, $cursorPosition)\n\n")
+
+	// Collect the paths of all subcommands in the tree.
+	var paths []string
+	walkSubCommands(c.subcommands, func(cmd *subcommand) {
+		paths = append(paths, psQuote(bashCmdPath(cmd)))
+	})
+
+	// Find the subcommand path and the previous word before the cursor.
+	fmt.Fprintf(w, "    # Find the subcommand context. e.g /db/migrate\n")
+	fmt.Fprintf(w, "    $path = ''\n")
+	fmt.Fprintf(w, "    $prev = ''\n")
+	fmt.Fprintf(w, "    foreach ($element in $commandAst.CommandElements | Select-Object -Skip 1) {\n")
+	fmt.Fprintf(w, "        if ($element.Extent.EndOffset -ge $cursorPosition) { break }\n")
+	fmt.Fprintf(w, "        $word = $element.ToString()\n\n")
+	fmt.Fprintf(w, "        # Stop completing flags after the -- terminator\n")
+	fmt.Fprintf(w, "        if ($word -eq '--') { return }\n")
+	if len(paths) > 0 {
+		fmt.Fprintf(w, "        if (@(%s) -contains \"$path/$word\") { $path = \"$path/$word\" }\n", strings.Join(paths, ", "))
 	}
-
-	return nil
+	fmt.Fprintf(w, "        $prev = $word\n")
+	fmt.Fprintf(w, "    }\n\n")
+
+	// Suggest a completion result if it matches the word before the cursor.
+	fmt.Fprintf(w, "    function Write-Candidate([string]$text, [string]$type, [string]$tooltip) {\n")
+	fmt.Fprintf(w, "        if ($text.StartsWith($wordToComplete)) {\n")
+	fmt.Fprintf(w, "            [System.Management.Automation.CompletionResult]::new($text, $text, $type, $tooltip)\n")
+	fmt.Fprintf(w, "        }\n")
+	fmt.Fprintf(w, "    }\n\n")
+
+	fmt.Fprintf(w, "    switch ($path) {\n")
+	writePowerShellContext(w, "''", c.flags, c.subcommands)
+	walkSubCommands(c.subcommands, func(cmd *subcommand) {
+		writePowerShellContext(w, psQuote(bashCmdPath(cmd)), cmd.allFlags(), cmd.subcommands)
+	})
+	fmt.Fprintf(w, "    }\n")
+	fmt.Fprintf(w, "}\n")
 }
 
-// addSourceLineIfMissing adds a line to the beginning of a shell rc file if it's not already present
-func addSourceLineIfMissing(rcPath, line, checkStr string) error {
-	// Read existing content
-	content, err := os.ReadFile(rcPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			// File doesn't exist, create it
-			return os.WriteFile(rcPath, []byte(line+"\n"), 0644)
+// Write the PowerShell switch branch for a subcommand context.
+// Flags that need arguments are handled first, include both long and short forms for matching.
+func writePowerShellContext(w io.Writer, pattern string, flags []*Flag, subcommands []*subcommand) {
+	fmt.Fprintf(w, "        %s {\n", pattern)
+
+	var valueFlags []*Flag
+	for _, f := range flags {
+		if f.takesValue() {
+			valueFlags = append(valueFlags, f)
+		}
+	}
+
+	if len(valueFlags) > 0 {
+		fmt.Fprintf(w, "            switch ($prev) {\n")
+		for _, f := range valueFlags {
+			names := []string{psQuote("--" + f.name)}
+			if f.shortName != "" {
+				names = append(names, psQuote("-"+f.shortName))
+			}
+			fmt.Fprintf(w, "                { $_ -in %s } {\n", strings.Join(names, ", "))
+			for _, value := range f.enum {
+				fmt.Fprintf(w, "                    Write-Candidate %s 'ParameterValue' %s\n", psQuote(value), psQuote(value))
+			}
+			if f.flagType == flagDirPath {
+				fmt.Fprintf(w, "                    [System.Management.Automation.CompletionCompleters]::CompleteFilename($wordToComplete) |\n")
+				fmt.Fprintf(w, "                        Where-Object ResultType -eq 'ProviderContainer'\n")
+			}
+			fmt.Fprintf(w, "                    return\n")
+			fmt.Fprintf(w, "                }\n")
 		}
-		return err
+		fmt.Fprintf(w, "            }\n")
 	}
 
-	// Check if the line or similar already exists
-	if strings.Contains(string(content), checkStr) {
-		return nil // Already present
+	for _, cmd := range subcommands {
+		fmt.Fprintf(w, "            Write-Candidate %s 'Command' %s\n", psQuote(cmd.name), psTooltip(cmd.name, cmd.description))
 	}
 
-	// Prepend the line (add to beginning)
-	newContent := line + "\n" + string(content)
-	return os.WriteFile(rcPath, []byte(newContent), 0644)
+	// Only show long-form flags in completions
+	for _, f := range flags {
+		fmt.Fprintf(w, "            Write-Candidate %s 'ParameterName' %s\n", psQuote("--"+f.name), psTooltip(f.name, f.usage))
+		if f.negatable {
+			fmt.Fprintf(w, "            Write-Candidate %s 'ParameterName' %s\n", psQuote("--no-"+f.name), psQuote("Turn off --"+f.name))
+		}
+	}
+	fmt.Fprintf(w, "        }\n")
 }
 
-// uninstallCompletion removes the completion script and cleans up rc files
-func uninstallCompletion(shell, binName string) error {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
+// Quote a string for PowerShell. Single quotes are escaped by doubling them.
+func psQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// Returns the quoted tooltip of a completion result.
+// PowerShell rejects empty tooltips, so the name is used instead.
+func psTooltip(name, description string) string {
+	if description == "" {
+		description = name
 	}
+	return psQuote(description)
+}
 
-	switch shell {
-	case "bash":
-		// Remove completion script
-		completionPath := filepath.Join(homeDir, ".bash_completion.d", binName)
-		if err := os.Remove(completionPath); err != nil && !os.IsNotExist(err) {
-			return fmt.Errorf("failed to remove completion script: %w", err)
+// GenNushellCompletion generates Nushell extern definitions and writes them to w.
+// There is an extern for the program and for each subcommand, e.g "myapp db migrate",
+// with its flags and positional arguments. Subcommand names are completed with
+// a custom completer of the first positional argument.
+//
+// Flags of type FilePath and DirPath complete files and directories.
+// Source the script from the Nushell config to use it.
+func (c *CLI) GenNushellCompletion(w io.Writer) {
+	binName := c.binName()
+
+	fmt.Fprintf(w, "# Nushell completion for %s\n", binName)
+	fmt.Fprintf(w, "# Generated by goflag\n")
+
+	writeNushellExtern(w, binName, c.flags, c.args, c.subcommands)
+	walkSubCommands(c.subcommands, func(cmd *subcommand) {
+		writeNushellExtern(w, binName+" "+cmd.Path(), cmd.allFlags(), cmd.args, cmd.subcommands)
+	})
+}
+
+// Write the extern definition of a command, preceded by the completer of its subcommands.
+func writeNushellExtern(w io.Writer, name string, flags, args []*Flag, subcommands []*subcommand) {
+	completer := nuQuote("nu-complete " + name)
+
+	if len(subcommands) > 0 {
+		fmt.Fprintf(w, "\ndef %s [] {\n", completer)
+		fmt.Fprintf(w, "    [\n")
+		for _, cmd := range subcommands {
+			fmt.Fprintf(w, "        { value: %s, description: %s }\n", nuQuote(cmd.name), nuQuote(cmd.description))
 		}
+		fmt.Fprintf(w, "    ]\n")
+		fmt.Fprintf(w, "}\n")
+	}
 
-		// Remove source line from .bashrc
-		bashrcPath := filepath.Join(homeDir, ".bashrc")
-		if err := removeSourceLine(bashrcPath, completionPath); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: Could not update .bashrc: %v\n", err)
-			fmt.Fprintf(os.Stderr, "You may need to manually remove the source line for: %s\n", completionPath)
+	// The completers of the enumerated values of flags and arguments.
+	enumType := func(f *Flag, typ string) string {
+		if len(f.enum) == 0 {
+			return typ
 		}
+		return typ + "@" + nuQuote("nu-complete "+name+" "+f.name)
+	}
 
-		fmt.Printf("Bash completion uninstalled from: %s\n", completionPath)
-		fmt.Println("Run 'source ~/.bashrc' or restart your terminal to complete removal")
+	for _, f := range slices.Concat(flags, args) {
+		if len(f.enum) == 0 || (!f.positional && !f.takesValue()) {
+			continue
+		}
 
-	case "zsh":
-		// Remove completion script
-		completionPath := filepath.Join(homeDir, ".zsh", "completion", "_"+binName)
-		if err := os.Remove(completionPath); err != nil && !os.IsNotExist(err) {
-			return fmt.Errorf("failed to remove completion script: %w", err)
+		values := make([]string, len(f.enum))
+		for i, value := range f.enum {
+			values[i] = nuQuote(value)
 		}
+		fmt.Fprintf(w, "\ndef %s [] {\n", nuQuote("nu-complete "+name+" "+f.name))
+		fmt.Fprintf(w, "    [%s]\n", strings.Join(values, " "))
+		fmt.Fprintf(w, "}\n")
+	}
 
-		fmt.Printf("Zsh completion uninstalled from: %s\n", completionPath)
-		fmt.Println("Note: fpath and compinit lines remain in .zshrc for other completions")
-		fmt.Println("Run 'source ~/.zshrc' or restart your terminal to complete removal")
+	fmt.Fprintf(w, "\nexport extern %s [\n", nuQuote(name))
+	for _, f := range flags {
+		spec := "--" + f.name
+		if utf8.RuneCountInString(f.shortName) == 1 {
+			spec += "(-" + f.shortName + ")"
+		}
+		if f.takesValue() {
+			spec += ": " + enumType(f, nuFlagType(f))
+		}
+		fmt.Fprintf(w, "    %s%s\n", spec, nuComment(f.usage))
+		if f.negatable {
+			fmt.Fprintf(w, "    --no-%s%s\n", f.name, nuComment("Turn off --"+f.name))
+		}
+	}
 
-	default:
-		return fmt.Errorf("unsupported shell: %s", shell)
+	// The subcommand or the positional arguments of the command.
+	if len(subcommands) > 0 {
+		fmt.Fprintf(w, "    command?: string@%s\n", completer)
+		fmt.Fprintf(w, "    ...args: string\n")
+	} else {
+		optional := false
+		for _, arg := range args {
+			optional = optional || !arg.required
+			typ := enumType(arg, "string")
+			switch {
+			case arg.variadic:
+				fmt.Fprintf(w, "    ...%s: %s%s\n", arg.name, typ, nuComment(arg.usage))
+			case optional:
+				fmt.Fprintf(w, "    %s?: %s%s\n", arg.name, typ, nuComment(arg.usage))
+			default:
+				fmt.Fprintf(w, "    %s: %s%s\n", arg.name, typ, nuComment(arg.usage))
+			}
+		}
 	}
+	fmt.Fprintf(w, "]\n")
+}
 
-	return nil
+// Returns the Nushell type of the value of a flag.
+func nuFlagType(f *Flag) string {
+	switch f.flagType {
+	case flagInt, flagInt64:
+		return "int"
+	case flagFloat32, flagFloat64:
+		return "number"
+	case flagFilePath:
+		return "path"
+	case flagDirPath:
+		return "directory"
+	default:
+		return "string"
+	}
 }
 
-// removeSourceLine removes a line containing the checkStr from the rc file
-func removeSourceLine(rcPath, checkStr string) error {
-	content, err := os.ReadFile(rcPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil // File doesn't exist, nothing to remove
-		}
-		return err
+// Returns the description of a parameter as a trailing Nushell comment.
+func nuComment(usage string) string {
+	if usage == "" {
+		return ""
 	}
+	return " # " + strings.Join(strings.Fields(usage), " ")
+}
 
-	lines := strings.Split(string(content), "\n")
-	var newLines []string
+// Quote a string for Nushell in double quotes.
+func nuQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
 
-	for _, line := range lines {
-		// Skip lines that contain the completion path
-		if !strings.Contains(line, checkStr) {
-			newLines = append(newLines, line)
+// Returns the function that generates the completion script of a shell.
+// Dynamic scripts call back into the program for candidates.
+func (c *CLI) completionGenerator(shell string, dynamic bool) (func(io.Writer), error) {
+	if dynamic {
+		switch shell {
+		case "bash":
+			return c.GenBashDynamicCompletion, nil
+		case "zsh":
+			return c.GenZshDynamicCompletion, nil
 		}
+		return nil, fmt.Errorf("dynamic completion is not supported for %s", shell)
 	}
 
-	// Only write if we actually removed something
-	if len(newLines) != len(lines) {
-		return os.WriteFile(rcPath, []byte(strings.Join(newLines, "\n")), 0644)
+	switch shell {
+	case "bash":
+		return c.GenBashCompletion, nil
+	case "zsh":
+		return c.GenZshCompletion, nil
+	case "fish":
+		return c.GenFishCompletion, nil
+	case "powershell":
+		return c.GenPowerShellCompletion, nil
+	case "nushell":
+		return c.GenNushellCompletion, nil
 	}
-
-	return nil
+	return nil, fmt.Errorf("unsupported shell: %s", shell)
 }
@@ -0,0 +1,142 @@
+package goflag
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenFishCompletion(t *testing.T) {
+	var config, dir, name, direction string
+	var color bool
+	var steps int
+	var files []string
+
+	cli := New()
+	cli.FilePath("config", "c", &config, "Config file")
+	cli.Bool("color", "", &color, "Use 'color'").Negatable()
+	cli.SubCommand("db", "Database commands", func() {}).
+		DirPath("dir", "d", &dir, "Data directory").
+		String("name", "n", &name, "Database name").
+		SubCommand("migrate", "Run migrations", func() {}).
+		Int("steps", "s", &steps, "Number of steps").
+		Arg("direction", &direction, "Direction").Enum("up", "down").
+		Arg("files", &files, "Migration files")
+
+	var out bytes.Buffer
+	cli.GenFishCompletion(&out)
+	script := out.String()
+
+	binName := filepath.Base(os.Args[0])
+	using := func(path string) string {
+		return "complete -c " + binName + " -n '__" + strings.NewReplacer("-", "_", ".", "_").Replace(binName) + `_using_path "` + path + `"'`
+	}
+
+	for _, want := range []string{
+		"case /completion /db /db/migrate\n",
+		"complete -c " + binName + " -f\n",
+		using("") + " -a 'db' -d 'Database commands'\n",
+		using("") + " -l 'config' -s 'c' -r -F -d 'Config file'\n",
+		using("") + ` -l 'color' -d 'Use \'color\''` + "\n",
+		using("") + " -l 'no-color' -d 'Turn off --color'\n",
+		using("/db") + " -a 'migrate' -d 'Run migrations'\n",
+		using("/db") + " -l 'dir' -s 'd' -x -a '(__fish_complete_directories)' -d 'Data directory'\n",
+		using("/db") + " -l 'name' -s 'n' -x -d 'Database name'\n",
+		using("/db/migrate") + " -l 'steps' -s 's' -x -d 'Number of steps'\n",
+		using("/db/migrate") + " -l 'dir' -s 'd' -x",
+		using("/db/migrate") + " -a 'up down' -d 'Direction'\n",
+		using("/db/migrate") + " -F\n",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Expected fish completion to contain %q, got:\n%s", want, script)
+		}
+	}
+
+	// Files are only completed where arguments are declared.
+	if strings.Count(script, " -F\n") != 1 {
+		t.Errorf("Expected a single file rule for the migrate arguments, got:\n%s", script)
+	}
+
+	// The completion command generates fish completions.
+	out.Reset()
+	cli.SetOutput(&out)
+	subcmd, err := cli.Parse([]string{"app", "completion", "--shell", "fish"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+	subcmd.Handler()
+
+	if !strings.HasPrefix(out.String(), "# Fish completion for") {
+		t.Errorf("Expected fish completions, got:\n%s", out.String())
+	}
+}
+
+var updateGolden = flag.Bool("update", false, "update the golden files in testdata")
+
+// Returns a CLI with nested subcommands and flags of every completion kind.
+func newCompletionCLI() *CLI {
+	var (
+		config, format, dir, name string
+		target                    string
+		color                     = true
+		verbose, steps            int
+		ratio                     float64
+		files                     []string
+	)
+
+	cli := New().SetName("app")
+	cli.FilePath("config", "c", &config, "Config file")
+	cli.Bool("color", "", &color, `Use "color" (it's nice)`).Negatable()
+	cli.Count("verbose", "v", &verbose, "Verbosity")
+	cli.String("format", "f", &format, "Output format").Enum("json", "yaml")
+	cli.SubCommand("db", "Database commands", func() {}).
+		DirPath("dir", "d", &dir, "Data directory").
+		String("name", "n", &name, "Database name").
+		SubCommand("migrate", "Run migrations", func() {}).
+		Int("steps", "s", &steps, "Number of steps").
+		Float64("ratio", "", &ratio, "").
+		Arg("target", &target, "Target version").Required().
+		Arg("files", &files, "Migration files")
+	return cli
+}
+
+func TestCompletionGolden(t *testing.T) {
+	cli := newCompletionCLI()
+	generators := map[string]func(io.Writer){
+		"app.bash": cli.GenBashCompletion,
+		"app.zsh":  cli.GenZshCompletion,
+		"app.fish": cli.GenFishCompletion,
+		"app.ps1":  cli.GenPowerShellCompletion,
+		"app.nu":   cli.GenNushellCompletion,
+
+		"app-dynamic.bash": cli.GenBashDynamicCompletion,
+		"app-dynamic.zsh":  cli.GenZshDynamicCompletion,
+	}
+
+	for file, generate := range generators {
+		t.Run(file, func(t *testing.T) {
+			var out bytes.Buffer
+			generate(&out)
+
+			path := filepath.Join("testdata", "completion", file)
+			if *updateGolden {
+				if err := os.WriteFile(path, out.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read golden file: %v", err)
+			}
+
+			if out.String() != string(want) {
+				t.Errorf("Completion script does not match %s, run go test -update:\n%s", path, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package goflag
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ConfigFile sets the path of a configuration file read by Parse.
+// Flags that are not given on the command line or in the environment
+// take their value from the file. A path given by a flag marked with
+// Flag.ConfigFile on the command line takes precedence over this path.
+// A missing file at this path is ignored.
+//
+// The format is chosen by the file extension: .json, .toml, .yaml/.yml and
+// .ini/.cfg/.conf. Other formats need a decoder set with CLI.ConfigDecoder.
+//
+// Top-level keys set global flags, sections (tables or nested objects) set the
+// flags of the subcommand with the same name. Nested subcommands use nested or
+// dotted sections. e.g
+//
+//	port = 8080
+//
+//	[greet]
+//	name = "John"
+//
+//	[db.migrate]
+//	steps = 3
+//
+// Arrays set slice flags, and arrays of key=value pairs or sections named
+// after a map flag set map flags. Underscores in keys match dashes in flag names.
+func (c *CLI) ConfigFile(path string) *CLI {
+	c.configPath = path
+	return c
+}
+
+// ConfigFile marks a global string flag as the path of the configuration file.
+// See CLI.ConfigFile for the file format. A path given on the command line or
+// in the environment must exist; the default path is ignored if it is missing.
+// It panics if the flag is not a string flag.
+func (flag *Flag) ConfigFile() *Flag {
+	if _, ok := flag.value.(*string); !ok {
+		panic(fmt.Errorf("config file flag %s must be a string flag", flag.name))
+	}
+	flag.configFile = true
+	return flag
+}
+
+// A single value read from a configuration file.
+type configValue struct {
+	key    string   // key as written in the file. e.g greet.name
+	values []string // the scalar value or the elements of an array.
+	list   bool     // the value is an array.
+}
+
+// A configuration file flattened to dotted keys.
+type configFile struct {
+	path   string
+	values map[string]*configValue // indexed by normalized key.
+}
+
+// Normalize a config key so that underscores match dashes in flag names.
+func normalizeConfigKey(key string) string {
+	return strings.ReplaceAll(key, "_", "-")
+}
+
+// Returns the config key of a flag defined by cmd. cmd is nil for global flags.
+func configKey(flag *Flag, cmd *subcommand) string {
+	if cmd == nil {
+		return flag.name
+	}
+	return strings.ReplaceAll(cmd.Path(), " ", ".") + "." + flag.name
+}
+
+// Read and decode a configuration file.
+func (c *CLI) readConfigFile(path string) (*configFile, error) {
+	decode := c.configDecoder(filepath.Ext(path))
+	if decode == nil {
+		return nil, fmt.Errorf("unsupported config file format: %s", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	object, err := decode(data)
+	if err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineAt(data, syntaxErr.Offset), err)
+		}
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	mapKeys := make(map[string]bool)
+	for key, flag := range c.configFlags() {
+		if _, ok := mapElemType[flag.flagType]; ok {
+			mapKeys[key] = true
+		}
+	}
+
+	values := make(map[string]*configValue)
+	if err := flattenConfig(values, "", object, mapKeys); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &configFile{path: path, values: values}, nil
+}
+
+// Returns the global flag marked with Flag.ConfigFile or nil.
+func (c *CLI) configFlag() *Flag {
+	for _, flag := range c.flags {
+		if flag.configFile {
+			return flag
+		}
+	}
+	return nil
+}
+
+// Load the configuration file of the CLI after the global flags were set
+// from the command line and environment. Returns nil if there is no file.
+func (c *CLI) loadConfig() (*configFile, error) {
+	var path string
+	explicit := false
+
+	flag := c.configFlag()
+	if flag != nil && flag.Changed() {
+		path, explicit = *flag.value.(*string), true
+	} else if c.configPath != "" {
+		path = c.configPath
+	} else if flag != nil {
+		path = *flag.value.(*string)
+	}
+
+	if path == "" {
+		return nil, nil
+	}
+
+	config, err := c.readConfigFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if err := c.checkConfigKeys(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Returns the flags of the CLI and its subcommands that may be set from a
+// configuration file, by normalized config key.
+func (c *CLI) configFlags() map[string]*Flag {
+	flags := make(map[string]*Flag)
+	add := func(defined []*Flag, cmd *subcommand) {
+		for _, flag := range defined {
+			if !flag.configFile && !isHelpFlag(flag.name) {
+				flags[normalizeConfigKey(configKey(flag, cmd))] = flag
+			}
+		}
+	}
+
+	add(c.flags, nil)
+	walkSubCommands(c.subcommands, func(cmd *subcommand) {
+		add(cmd.flags, cmd)
+	})
+	return flags
+}
+
+// Reject keys that do not match any flag of the CLI or its subcommands.
+func (c *CLI) checkConfigKeys(config *configFile) error {
+	known := c.configFlags()
+
+	keys := make([]string, 0, len(config.values))
+	for key := range config.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if known[key] == nil {
+			return fmt.Errorf("%s: unknown key %s", config.path, config.values[key].key)
+		}
+	}
+	return nil
+}
+
+// Set the flags that were not given on the command line or in the environment
+// from the configuration file. cmd is the subcommand that defines the flags,
+// nil for global flags. A nil config is a no-op.
+func (config *configFile) apply(flags []*Flag, cmd *subcommand) error {
+	if config == nil {
+		return nil
+	}
+
+	for _, flag := range flags {
+		if flag.Changed() || flag.value == nil || flag.positional || flag.configFile {
+			continue
+		}
+
+		value, ok := config.values[normalizeConfigKey(configKey(flag, cmd))]
+		if !ok {
+			continue
+		}
+
+		if err := setConfigValue(flag, value); err != nil {
+			return fmt.Errorf("%s: invalid value for key %s: %v", config.path, value.key, err)
+		}
+
+		if err := validateFlagValue(flag); err != nil {
+			return fmt.Errorf("%s: key %s: %v", config.path, value.key, err)
+		}
+		flag.source = SourceConfig
+	}
+	return nil
+}
+
+// Parse a config value into the flag. Arrays replace the default of slice
+// flags, and arrays of key=value pairs the default of map flags.
+func setConfigValue(flag *Flag, value *configValue) error {
+	if !value.list {
+		return parseFlagValue(flag, value.values[0])
+	}
+
+	if _, ok := mapElemType[flag.flagType]; ok {
+		reflect.ValueOf(flag.value).Elem().SetZero()
+		for _, pair := range value.values {
+			if err := setMapValue(flag, pair, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if _, ok := sliceElemType[flag.flagType]; !ok {
+		return fmt.Errorf("expected a single value, got an array")
+	}
+
+	slice := reflect.ValueOf(flag.value).Elem()
+	slice.Set(reflect.Zero(slice.Type()))
+	for _, elem := range value.values {
+		if err := appendSliceValue(flag, elem); err != nil {
+			return err
+		}
+	}
+	return nil
+}
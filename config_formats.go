@@ -0,0 +1,286 @@
+package goflag
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigDecoder decodes the content of a configuration file into nested maps.
+// Nested maps are sections or set map flags, slices set slice and map flags
+// and every other value is formatted with fmt.Sprint, times in the layout of
+// time flags. Nil values are ignored.
+type ConfigDecoder func(data []byte) (map[string]any, error)
+
+// ConfigDecoder sets the decoder of configuration files with one of the given
+// extensions, including the leading dot. It replaces the built-in decoder of
+// an extension. e.g to read .conf files as JSON:
+//
+//	cli.ConfigDecoder(func(data []byte) (map[string]any, error) {
+//	    var config map[string]any
+//	    err := json.Unmarshal(data, &config)
+//	    return config, err
+//	}, ".conf")
+//
+// It panics if no extension is given.
+func (c *CLI) ConfigDecoder(decode ConfigDecoder, extensions ...string) *CLI {
+	if len(extensions) == 0 {
+		panic("config decoder requires at least one extension")
+	}
+
+	if c.configDecoders == nil {
+		c.configDecoders = make(map[string]ConfigDecoder)
+	}
+
+	for _, ext := range extensions {
+		c.configDecoders[strings.ToLower(ext)] = decode
+	}
+	return c
+}
+
+// The built-in decoders by file extension.
+var builtinConfigDecoders = map[string]ConfigDecoder{
+	".json": decodeJSONConfig,
+	".toml": decodeTOMLConfig,
+	".yaml": decodeYAMLConfig,
+	".yml":  decodeYAMLConfig,
+	".ini":  decodeINIConfig,
+	".cfg":  decodeINIConfig,
+	".conf": decodeINIConfig,
+}
+
+// Returns the decoder of a file extension or nil if the format is not supported.
+func (c *CLI) configDecoder(ext string) ConfigDecoder {
+	ext = strings.ToLower(ext)
+	if decode, ok := c.configDecoders[ext]; ok {
+		return decode
+	}
+	return builtinConfigDecoders[ext]
+}
+
+// Decode a JSON object. Numbers are kept as written.
+func decodeJSONConfig(data []byte) (map[string]any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var root map[string]any
+	if err := decoder.Decode(&root); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// Decode a TOML document. Tables are nested maps.
+func decodeTOMLConfig(data []byte) (map[string]any, error) {
+	var root map[string]any
+	if err := toml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// Decode a YAML document. Mappings are nested maps.
+func decodeYAMLConfig(data []byte) (map[string]any, error) {
+	var root map[string]any
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// Keys and section names of INI files.
+var iniNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)*$`)
+
+// Decode an INI document. The accepted syntax is:
+//
+//	; a comment on its own line, also with #
+//	key = value
+//	key: value
+//	[section]
+//	[section.nested]
+//
+// Keys and sections are made of letters, digits, dashes and underscores.
+// Dotted sections are nested. Values are strings, optionally in single or
+// double quotes; double quoted values support Go escapes. Lists are given
+// as comma separated values. A ; or # after a value is part of the value.
+// Anything else, including continuation lines and duplicate keys, is an error.
+func decodeINIConfig(data []byte) (map[string]any, error) {
+	root := make(map[string]any)
+	section := root
+
+	for i, line := range strings.Split(string(data), "\n") {
+		lineNo := i + 1
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+
+		if line[0] == '[' {
+			name, ok := strings.CutSuffix(line[1:], "]")
+			if !ok || !iniNamePattern.MatchString(name) {
+				return nil, fmt.Errorf("line %d: invalid section header %s", lineNo, line)
+			}
+
+			var err error
+			if section, err = iniSection(root, name); err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			continue
+		}
+
+		idx := strings.IndexAny(line, "=:")
+		if idx < 0 {
+			return nil, fmt.Errorf("line %d: expected key = value, got %s", lineNo, line)
+		}
+
+		key := strings.TrimSpace(line[:idx])
+		if !iniNamePattern.MatchString(key) || strings.Contains(key, ".") {
+			return nil, fmt.Errorf("line %d: invalid key %q", lineNo, key)
+		}
+
+		value, err := unquoteINIValue(strings.TrimSpace(line[idx+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+
+		if _, ok := section[key]; ok {
+			return nil, fmt.Errorf("line %d: duplicate key %s", lineNo, key)
+		}
+		section[key] = value
+	}
+	return root, nil
+}
+
+// Returns the map of a dotted INI section, creating it if it is missing.
+func iniSection(root map[string]any, name string) (map[string]any, error) {
+	section := root
+	for _, part := range strings.Split(name, ".") {
+		switch v := section[part].(type) {
+		case nil:
+			child := make(map[string]any)
+			section[part] = child
+			section = child
+		case map[string]any:
+			section = v
+		default:
+			return nil, fmt.Errorf("section %s conflicts with key %s", name, part)
+		}
+	}
+	return section, nil
+}
+
+// Remove the quotes of a quoted INI value. Unquoted values are returned as is.
+func unquoteINIValue(s string) (string, error) {
+	if s == "" || (s[0] != '"' && s[0] != '\'') {
+		return s, nil
+	}
+
+	if len(s) < 2 || s[len(s)-1] != s[0] {
+		return "", fmt.Errorf("unterminated string %s", s)
+	}
+
+	if s[0] == '\'' {
+		if strings.Contains(s[1:len(s)-1], "'") {
+			return "", fmt.Errorf("invalid string %s", s)
+		}
+		return s[1 : len(s)-1], nil
+	}
+
+	value, err := strconv.Unquote(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid string %s", s)
+	}
+	return value, nil
+}
+
+// Returns the 1-based line of a byte offset in data.
+func lineAt(data []byte, offset int64) int {
+	return 1 + bytes.Count(data[:min(int(offset), len(data))], []byte("\n"))
+}
+
+// Store the values of a decoded object with dotted keys, rejecting
+// values that can not set a flag. Nested objects are sections, except
+// for the keys in mapKeys, whose objects set map flags.
+func flattenConfig(values map[string]*configValue, section string, object map[string]any, mapKeys map[string]bool) error {
+	for name, raw := range object {
+		key := name
+		if section != "" {
+			key = section + "." + name
+		}
+
+		if raw == nil {
+			continue
+		}
+
+		value := &configValue{key: key}
+		nested, isObject := raw.(map[string]any)
+		switch rv := reflect.ValueOf(raw); {
+		case isObject && mapKeys[normalizeConfigKey(key)]:
+			// A map flag. e.g "labels": {"env": "prod"}
+			value.list = true
+			for k, elem := range nested {
+				if !isConfigScalar(elem) {
+					return fmt.Errorf("key %s.%s: only scalar map values are supported", key, k)
+				}
+				value.values = append(value.values, k+"="+configString(elem))
+			}
+			sort.Strings(value.values)
+		case isObject:
+			if err := flattenConfig(values, key, nested, mapKeys); err != nil {
+				return err
+			}
+			continue
+		case rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array:
+			value.list = true
+			for i := range rv.Len() {
+				elem := rv.Index(i).Interface()
+				if !isConfigScalar(elem) {
+					return fmt.Errorf("key %s: only arrays of scalars are supported", key)
+				}
+				value.values = append(value.values, configString(elem))
+			}
+		case rv.Kind() == reflect.Map:
+			return fmt.Errorf("key %s: unsupported value of type %T", key, raw)
+		default:
+			value.values = []string{configString(raw)}
+		}
+
+		normalized := normalizeConfigKey(key)
+		if _, ok := values[normalized]; ok {
+			return fmt.Errorf("duplicate key %s", key)
+		}
+		values[normalized] = value
+	}
+	return nil
+}
+
+// Format a decoded scalar like a command line value.
+// Times use the layout of time flags, see ParseTime.
+func configString(v any) string {
+	if t, ok := v.(time.Time); ok {
+		return t.Format("2006-01-02T15:04 MST")
+	}
+	return fmt.Sprint(v)
+}
+
+// Returns true if v is not nil, a slice or a map.
+func isConfigScalar(v any) bool {
+	if v == nil {
+		return false
+	}
+
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,299 @@
+package goflag
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigFormats(t *testing.T) {
+	// A decoder set for an extension, e.g for a format without a built-in decoder.
+	decode := func(data []byte) (map[string]any, error) {
+		return map[string]any{
+			"port":    int64(9090),
+			"dry-run": true,
+			"origins": []string{"a.com", "b.com"},
+			"labels":  map[string]any{"env": "prod", "team": "web"},
+			"timeout": nil,
+			"greet":   map[string]any{"name": "John"},
+			"db":      map[string]any{"migrate": map[string]any{"steps": 3}},
+		}, nil
+	}
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"config.json", `{
+			"port": 9090,
+			"dry_run": true,
+			"origins": ["a.com", "b.com"],
+			"labels": {"env": "prod", "team": "web"},
+			"greet": {"name": "John"},
+			"db": {"migrate": {"steps": 3}}
+		}`},
+		{"config.toml", `
+# global flags
+port = 9_090
+dry_run = true
+origins = [
+  "a.com", # first
+  "b.com",
+]
+
+[labels]
+env = "prod"
+team = "web"
+
+[greet]
+name = "John"
+
+[db.migrate]
+steps = 3
+`},
+		{"config.yaml", `
+---
+port: 9090
+dry-run: true
+timeout:
+origins:
+  - a.com
+  - "b.com"
+labels:
+  env: prod
+  team: web
+greet:
+  name: 'John' # quoted
+db:
+  migrate:
+    steps: 3
+`},
+		{"config.ini", `
+; global flags
+port = 9090
+dry-run = true
+origins = a.com,b.com
+
+[labels]
+env = prod
+team: web
+
+[greet]
+name = "John"
+
+[db.migrate]
+steps: 3
+`},
+		{"config.props", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				port    int
+				dryRun  bool
+				origins []string
+				labels  map[string]string
+				name    string
+				steps   int
+				timeout = time.Second
+			)
+
+			cli := New().ConfigFile(writeConfig(t, test.name, test.content))
+			cli.ConfigDecoder(decode, ".props")
+			cli.Int("port", "p", &port, "Port")
+			cli.Bool("dry-run", "n", &dryRun, "Dry run")
+			cli.StringSlice("origins", "o", &origins, "Origins")
+			cli.StringMap("labels", "l", &labels, "Labels")
+			cli.Duration("timeout", "t", &timeout, "Timeout")
+			cli.SubCommand("greet", "Greet a person", func() {}).
+				String("name", "n", &name, "Name").Required()
+			cli.SubCommand("db", "Database", func() {}).
+				SubCommand("migrate", "Migrate", func() {}).
+				Int("steps", "s", &steps, "Steps")
+
+			if _, err := cli.Parse([]string{"app", "greet"}); err != nil {
+				t.Fatalf("Expected no error, but got '%v'", err)
+			}
+
+			if port != 9090 || !dryRun || name != "John" || timeout != time.Second {
+				t.Errorf("Unexpected values: port=%d dry-run=%v name=%q timeout=%v", port, dryRun, name, timeout)
+			}
+
+			if !reflect.DeepEqual(origins, []string{"a.com", "b.com"}) {
+				t.Errorf("Expected origins [a.com b.com], but got %v", origins)
+			}
+
+			if !reflect.DeepEqual(labels, map[string]string{"env": "prod", "team": "web"}) {
+				t.Errorf("Expected labels env=prod and team=web, but got %v", labels)
+			}
+
+			if _, err := cli.Parse([]string{"app", "db", "migrate"}); err != nil {
+				t.Fatalf("Expected no error, but got '%v'", err)
+			}
+
+			if steps != 3 {
+				t.Errorf("Expected steps 3, but got %d", steps)
+			}
+		})
+	}
+}
+
+func TestDecodeINIConfig(t *testing.T) {
+	got, err := decodeINIConfig([]byte("a = 1\nb: 'x=y'\nc = \"tab\\t\"\nd =\n[s.t]\nk = v\n"))
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	want := map[string]any{
+		"a": "1", "b": "x=y", "c": "tab\t", "d": "",
+		"s": map[string]any{"t": map[string]any{"k": "v"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, but got %v", want, got)
+	}
+
+	invalid := map[string]string{
+		"a = 1 ; comment\n":    "",
+		"\"a=b\" = 1\n":        "invalid key",
+		"a.b = 1\n":            "invalid key",
+		"a\n":                  "expected key = value",
+		"[a\n":                 "invalid section header",
+		"[]\n":                 "invalid section header",
+		"a = 1\na = 2\n":       "line 2: duplicate key a",
+		"a = 1\n[a]\n":         "section a conflicts with key a",
+		"a = \"unterminated\n": "unterminated string",
+		"a = 'it's'\n":         "invalid string",
+	}
+
+	for content, want := range invalid {
+		_, err := decodeINIConfig([]byte(content))
+		if want == "" {
+			// Inline comments are part of the value.
+			if err != nil {
+				t.Errorf("%q: expected no error, but got '%v'", content, err)
+			}
+			continue
+		}
+
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected error containing %q, but got %v", content, want, err)
+		}
+	}
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	var port, workers int
+	var config, name string
+
+	path := writeConfig(t, "app.json", `{"port": 1000, "workers": 4, "greet": {"name": "File"}}`)
+
+	newCLI := func() *CLI {
+		port, workers, name, config = 8080, 1, "Default", "missing.json"
+		cli := New()
+		cli.String("config", "c", &config, "Config file").ConfigFile()
+		cli.Int("port", "p", &port, "Port").Env("APP_PORT")
+		cli.Int("workers", "w", &workers, "Workers")
+		cli.SubCommand("greet", "Greet", func() {}).
+			String("name", "n", &name, "Name")
+		return cli
+	}
+
+	// A missing default config file is ignored.
+	if _, err := newCLI().Parse([]string{"app"}); err != nil {
+		t.Fatalf("Expected missing default config to be ignored, but got '%v'", err)
+	}
+
+	if port != 8080 || workers != 1 {
+		t.Errorf("Expected defaults, got port=%d workers=%d", port, workers)
+	}
+
+	// argv > env > file > default.
+	t.Setenv("APP_PORT", "2000")
+	if _, err := newCLI().Parse([]string{"app", "-c", path, "greet"}); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if port != 2000 || workers != 4 || name != "File" {
+		t.Errorf("Unexpected values: port=%d workers=%d name=%q", port, workers, name)
+	}
+
+	if _, err := newCLI().Parse([]string{"app", "-c", path, "-p", "3000", "greet", "-n", "Argv"}); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if port != 3000 || name != "Argv" {
+		t.Errorf("Expected argv to win, got port=%d name=%q", port, name)
+	}
+
+	// An explicit config file must exist.
+	_, err := newCLI().Parse([]string{"app", "--config", filepath.Join(t.TempDir(), "nope.json")})
+	if err == nil || !strings.Contains(err.Error(), "nope.json") {
+		t.Errorf("Expected error for missing config file, but got %v", err)
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	failing := func(data []byte) (map[string]any, error) {
+		return nil, errors.New("bad document")
+	}
+
+	nested := func(data []byte) (map[string]any, error) {
+		return map[string]any{"port": []any{[]any{1}}}, nil
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"bad.json", `{"port": 1, "greet": {"nmae": "x"}}`, "bad.json: unknown key greet.nmae"},
+		{"bad.json", `{"port": "abc"}`, "bad.json: invalid value for key port"},
+		{"bad.json", `{"port": [1, 2]}`, "expected a single value"},
+		{"bad.json", `{"port": 80}`, "bad.json: key port: invalid value (80)"},
+		{"bad.json", `{"port": [{"a": 1}]}`, "key port: only arrays of scalars are supported"},
+		{"bad.json", `{"dry_run": true, "dry-run": false}`, "bad.json: duplicate key dry"},
+		{"bad.json", `{"labels": {"a": [1]}}`, "key labels.a: only scalar map values are supported"},
+		{"bad.json", "{\n  \"port\": 1,\n  \"greet\": {\"name\": }\n}", "bad.json:3:"},
+		{"bad.toml", "port = 1\n[greet]\nname = \"unterminated\n", "bad.toml: toml: line 3"},
+		{"bad.toml", "port = 1000\nport = 2000\n", "bad.toml: toml: line 2"},
+		{"bad.toml", "\"po=rt\" = 1000\n", "bad.toml: unknown key po=rt"},
+		{"bad.yaml", "greet:\n  name: [a\n", "bad.yaml: yaml: line"},
+		{"bad.ini", "[greet]\nname\n", "bad.ini: line 2: expected key = value"},
+		{"bad.xml", "<port>1</port>", "unsupported config file format"},
+		{"bad.conf", "port = 1", "bad.conf: bad document"},
+		{"bad.nested", "", "key port: only arrays of scalars are supported"},
+	}
+
+	for _, test := range tests {
+		var port int
+		var name string
+		var labels map[string]string
+
+		cli := New().ConfigFile(writeConfig(t, test.name, test.content))
+		cli.ConfigDecoder(failing, ".conf")
+		cli.ConfigDecoder(nested, ".nested")
+		cli.Int("port", "p", &port, "Port").Validate(Min(100))
+		cli.StringMap("labels", "l", &labels, "Labels")
+		cli.SubCommand("greet", "Greet", func() {}).
+			String("name", "n", &name, "Name")
+
+		_, err := cli.Parse([]string{"app"})
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%q: expected error containing %q, but got %v", test.content, test.want, err)
+		}
+	}
+}
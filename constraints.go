@@ -0,0 +1,202 @@
+package goflag
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+type constraintKind int
+
+const (
+	constraintMutuallyExclusive constraintKind = iota
+	constraintRequiredTogether
+	constraintOneRequired
+	constraintRequiredIf
+)
+
+// A constraint between flags, checked by Parse after all sources are applied.
+type constraint struct {
+	kind  constraintKind
+	names []string // flag names. For RequiredIf, the required flag and the flag it depends on.
+	value string   // the value of the flag a RequiredIf depends on.
+}
+
+// Create a constraint on a group of at least two of the given flags.
+// It panics if a name is not one of flags, so that a typo is reported
+// when the constraint is added rather than by Parse.
+func newConstraint(kind constraintKind, names []string, flags []*Flag) constraint {
+	if len(names) < 2 {
+		panic(fmt.Errorf("a flag constraint needs at least two flags, got %v", names))
+	}
+
+	for _, name := range names {
+		if findFlag(flags, name) == nil {
+			panic(fmt.Errorf("flag constraint references unknown flag --%s", name))
+		}
+	}
+	return constraint{kind: kind, names: slices.Clone(names)}
+}
+
+// MutuallyExclusive adds a constraint that at most one of the named flags is given.
+// A flag is given if it is set on the command line, in the environment or in
+// the configuration file.
+//
+// The flags must be defined before the constraint. Constraints panic if a
+// name is not a flag.
+func (c *CLI) MutuallyExclusive(names ...string) *CLI {
+	c.constraints = append(c.constraints, newConstraint(constraintMutuallyExclusive, names, c.flags))
+	return c
+}
+
+// RequiredTogether adds a constraint that the named flags are either all given or none.
+func (c *CLI) RequiredTogether(names ...string) *CLI {
+	c.constraints = append(c.constraints, newConstraint(constraintRequiredTogether, names, c.flags))
+	return c
+}
+
+// OneRequired adds a constraint that at least one of the named flags is given.
+func (c *CLI) OneRequired(names ...string) *CLI {
+	c.constraints = append(c.constraints, newConstraint(constraintOneRequired, names, c.flags))
+	return c
+}
+
+// RequiredIf adds a constraint that flag is required when the value of otherFlag
+// equals value. The value is compared with the formatted value of otherFlag,
+// including its default. e.g RequiredIf("cert", "tls", "true")
+func (c *CLI) RequiredIf(flag, otherFlag, value string) *CLI {
+	con := newConstraint(constraintRequiredIf, []string{flag, otherFlag}, c.flags)
+	con.value = value
+	c.constraints = append(c.constraints, con)
+	return c
+}
+
+// MutuallyExclusive adds a constraint that at most one of the named flags is given.
+// The names may refer to flags inherited from the parents defined so far.
+// See CLI.MutuallyExclusive.
+func (cmd *subcommand) MutuallyExclusive(names ...string) *subcommand {
+	cmd.constraints = append(cmd.constraints, newConstraint(constraintMutuallyExclusive, names, cmd.allFlags()))
+	return cmd
+}
+
+// RequiredTogether adds a constraint that the named flags are either all given or none.
+// See CLI.RequiredTogether.
+func (cmd *subcommand) RequiredTogether(names ...string) *subcommand {
+	cmd.constraints = append(cmd.constraints, newConstraint(constraintRequiredTogether, names, cmd.allFlags()))
+	return cmd
+}
+
+// OneRequired adds a constraint that at least one of the named flags is given.
+// See CLI.OneRequired.
+func (cmd *subcommand) OneRequired(names ...string) *subcommand {
+	cmd.constraints = append(cmd.constraints, newConstraint(constraintOneRequired, names, cmd.allFlags()))
+	return cmd
+}
+
+// RequiredIf adds a constraint that flag is required when the value of otherFlag
+// equals value. See CLI.RequiredIf.
+func (cmd *subcommand) RequiredIf(flag, otherFlag, value string) *subcommand {
+	con := newConstraint(constraintRequiredIf, []string{flag, otherFlag}, cmd.allFlags())
+	con.value = value
+	cmd.constraints = append(cmd.constraints, con)
+	return cmd
+}
+
+// Join flag names for messages. e.g "--a, --b and --c"
+func joinFlagNames(names []string, conjunction string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = "--" + name
+	}
+
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " " + conjunction + " " + quoted[len(quoted)-1]
+}
+
+// Returns the description of the constraint used in the help and errors.
+func (con constraint) String() string {
+	switch con.kind {
+	case constraintMutuallyExclusive:
+		return fmt.Sprintf("flags %s are mutually exclusive", joinFlagNames(con.names, "and"))
+	case constraintRequiredTogether:
+		return fmt.Sprintf("flags %s must be given together", joinFlagNames(con.names, "and"))
+	case constraintOneRequired:
+		return fmt.Sprintf("one of the flags %s is required", joinFlagNames(con.names, "or"))
+	case constraintRequiredIf:
+		return fmt.Sprintf("flag --%s is required when --%s is %s", con.names[0], con.names[1], con.value)
+	}
+	return ""
+}
+
+// Check the constraint. The names are resolved in flags, they were checked
+// when the constraint was added.
+func (con constraint) check(flags []*Flag) error {
+	var given, missing []string
+	for _, name := range con.names {
+		if flag := findFlag(flags, name); flag.Changed() {
+			given = append(given, name)
+		} else {
+			missing = append(missing, name)
+		}
+	}
+
+	switch con.kind {
+	case constraintMutuallyExclusive:
+		if len(given) > 1 {
+			return fmt.Errorf("flags %s are mutually exclusive", joinFlagNames(given, "and"))
+		}
+	case constraintRequiredTogether:
+		if len(given) > 0 && len(missing) > 0 {
+			return fmt.Errorf("%s: missing %s", con, joinFlagNames(missing, "and"))
+		}
+	case constraintOneRequired:
+		if len(given) == 0 {
+			return fmt.Errorf("%s", con)
+		}
+	case constraintRequiredIf:
+		required, other := findFlag(flags, con.names[0]), findFlag(flags, con.names[1])
+		if !required.Changed() && other.Value().String() == con.value {
+			return fmt.Errorf("%s", con)
+		}
+	}
+	return nil
+}
+
+// Check all constraints against the given flags.
+func checkConstraints(constraints []constraint, flags []*Flag) error {
+	for _, con := range constraints {
+		if err := con.check(flags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Print the constraints to the writer.
+func printConstraints(constraints []constraint, w io.Writer, indent string) {
+	for _, con := range constraints {
+		fmt.Fprintf(w, "%s%s\n", indent, con)
+	}
+}
+
+// Returns the long names of the flags that are mutually exclusive with each flag.
+func exclusiveFlags(constraints []constraint) map[string][]string {
+	excluded := make(map[string][]string)
+	for _, con := range constraints {
+		if con.kind != constraintMutuallyExclusive {
+			continue
+		}
+
+		for _, name := range con.names {
+			for _, other := range con.names {
+				if other != name && !slices.Contains(excluded[name], other) {
+					excluded[name] = append(excluded[name], other)
+				}
+			}
+		}
+	}
+	return excluded
+}
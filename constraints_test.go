@@ -0,0 +1,137 @@
+package goflag
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestConstraints(t *testing.T) {
+	var (
+		jsonOut, yamlOut, tls bool
+		user, password, cert  string
+		mode                  = "dev"
+		key                   string
+	)
+
+	newCLI := func() *CLI {
+		jsonOut, yamlOut, tls = false, false, false
+		user, password, cert, mode, key = "", "", "", "dev", ""
+
+		cli := New()
+		cli.Bool("json", "j", &jsonOut, "JSON output")
+		cli.Bool("yaml", "y", &yamlOut, "YAML output")
+		cli.String("user", "u", &user, "User")
+		cli.String("password", "p", &password, "Password").Env("TEST_PASSWORD")
+		cli.MutuallyExclusive("json", "yaml").RequiredTogether("user", "password")
+
+		cli.SubCommand("serve", "Serve", func() {}).
+			Bool("tls", "t", &tls, "Enable TLS").
+			String("cert", "c", &cert, "Certificate").
+			String("mode", "m", &mode, "Mode").
+			String("key", "k", &key, "Signing key").
+			RequiredIf("cert", "tls", "true").
+			RequiredIf("key", "mode", "prod").
+			OneRequired("cert", "key")
+		return cli
+	}
+
+	valid := [][]string{
+		{"app"},
+		{"app", "--json"},
+		{"app", "-u", "me", "-p", "secret"},
+		{"app", "serve", "-t", "-c", "cert.pem"},
+		{"app", "-u", "me", "-p", "x", "serve", "-m", "prod", "-k", "key"},
+	}
+
+	for _, argv := range valid {
+		if _, err := newCLI().Parse(argv); err != nil {
+			t.Errorf("%v: expected no error, but got '%v'", argv, err)
+		}
+	}
+
+	invalid := []struct {
+		argv []string
+		want string
+	}{
+		{[]string{"app", "-j", "-y"}, "flags --json and --yaml are mutually exclusive"},
+		{[]string{"app", "-u", "me"}, "flags --user and --password must be given together: missing --password"},
+		{[]string{"app", "serve", "-t"}, "flag --cert is required when --tls is true"},
+		{[]string{"app", "-u", "me", "-p", "x", "serve", "-m", "prod"}, "flag --key is required when --mode is prod"},
+		{[]string{"app", "serve"}, "one of the flags --cert or --key is required"},
+	}
+
+	for _, test := range invalid {
+		_, err := newCLI().Parse(test.argv)
+		if err == nil || err.Error() != test.want {
+			t.Errorf("%v: expected error %q, but got %v", test.argv, test.want, err)
+		}
+	}
+
+	// Flags given in the environment count as given.
+	t.Setenv("TEST_PASSWORD", "secret")
+	if _, err := newCLI().Parse([]string{"app", "-u", "me"}); err != nil {
+		t.Errorf("Expected password from env to satisfy the constraint, but got '%v'", err)
+	}
+
+	// The constraints are shown in the help.
+	var out bytes.Buffer
+	cli := newCLI()
+	cli.PrintUsage(&out)
+	for _, want := range []string{
+		"Constraints:\n  flags --json and --yaml are mutually exclusive\n",
+		"  Constraints:\n    flag --cert is required when --tls is true\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected help to contain %q, got:\n%s", want, out.String())
+		}
+	}
+
+	// Mutually exclusive flags are excluded in zsh completions.
+	out.Reset()
+	cli.GenZshCompletion(&out)
+	if !strings.Contains(out.String(), "'(--yaml)--json[JSON output]'") {
+		t.Errorf("Expected zsh exclusion list, got:\n%s", out.String())
+	}
+}
+
+func TestConstraintErrors(t *testing.T) {
+	var install, verbose, quiet bool
+	cli := New()
+	cli.Bool("install", "i", &install, "Install")
+	cmd := cli.SubCommand("run", "Run", func() {}).
+		Bool("verbose", "v", &verbose, "Verbose").
+		SubCommand("test", "Test", func() {}).
+		Bool("quiet", "q", &quiet, "Quiet")
+
+	// The completion command uses a constraint for its flags.
+	_, err := New().Parse([]string{"app", "completion", "-s", "bash", "-i", "-u"})
+	if err == nil || err.Error() != "flags --install and --uninstall are mutually exclusive" {
+		t.Errorf("Expected mutually exclusive error, but got %v", err)
+	}
+
+	// Subcommand constraints may use inherited flags.
+	cmd.MutuallyExclusive("quiet", "verbose")
+
+	panics := []struct {
+		add  func()
+		want string
+	}{
+		{func() { cli.OneRequired("install") }, "a flag constraint needs at least two flags"},
+		{func() { cli.MutuallyExclusive("install", "missing") }, "unknown flag --missing"},
+		{func() { cli.RequiredIf("missing", "install", "true") }, "unknown flag --missing"},
+		{func() { cmd.RequiredTogether("quiet", "verbos") }, "unknown flag --verbos"},
+	}
+
+	for _, test := range panics {
+		func() {
+			defer func() {
+				if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), test.want) {
+					t.Errorf("Expected panic containing %q, but got %v", test.want, r)
+				}
+			}()
+			test.add()
+		}()
+	}
+}
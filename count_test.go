@@ -0,0 +1,90 @@
+package goflag
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCount(t *testing.T) {
+	var verbose, debug int
+	var force bool
+	var name string
+
+	cli := New()
+	cli.Count("verbose", "v", &verbose, "Verbosity level")
+	cli.Bool("force", "f", &force, "Force")
+	cli.SubCommand("greet", "Greet", func() {}).
+		Count("debug", "d", &debug, "Debug level").
+		String("name", "n", &name, "Name")
+
+	tests := []struct {
+		argv    []string
+		verbose int
+		debug   int
+		force   bool
+	}{
+		{[]string{"app"}, 0, 0, false},
+		{[]string{"app", "-v"}, 1, 0, false},
+		{[]string{"app", "-v", "-v", "-v"}, 3, 0, false},
+		{[]string{"app", "-vvv"}, 3, 0, false},
+		{[]string{"app", "--verbose", "-vv"}, 3, 0, false},
+		{[]string{"app", "--verbose=3"}, 3, 0, false},
+		{[]string{"app", "-vfv"}, 2, 0, true},
+		{[]string{"app", "-v", "greet", "-ddn", "John", "-d"}, 1, 3, false},
+	}
+
+	for _, test := range tests {
+		if _, err := cli.Parse(test.argv); err != nil {
+			t.Fatalf("%v: expected no error, but got '%v'", test.argv, err)
+		}
+
+		if verbose != test.verbose || debug != test.debug || force != test.force {
+			t.Errorf("%v: expected verbose=%d debug=%d force=%v, got verbose=%d debug=%d force=%v",
+				test.argv, test.verbose, test.debug, test.force, verbose, debug, force)
+		}
+		verbose, debug, force = 0, 0, false
+	}
+
+	// A count given with a value must be a non-negative integer.
+	for _, argv := range [][]string{{"app", "--verbose=x"}, {"app", "--verbose=-1"}} {
+		if _, err := cli.Parse(argv); err == nil {
+			t.Errorf("%v: expected an error", argv)
+		}
+	}
+}
+
+func TestCountDefault(t *testing.T) {
+	verbose := 2
+	cli := New()
+	cli.Count("verbose", "v", &verbose, "Verbosity level").Env("COUNT_VERBOSE")
+
+	// The first occurrence on the command line replaces the default.
+	if _, err := cli.Parse([]string{"app", "-v"}); err != nil || verbose != 1 {
+		t.Errorf("Expected verbose 1, got %d (%v)", verbose, err)
+	}
+
+	t.Setenv("COUNT_VERBOSE", "4")
+	if _, err := cli.Parse([]string{"app"}); err != nil || verbose != 4 {
+		t.Errorf("Expected verbose 4 from env, got %d (%v)", verbose, err)
+	}
+
+	var out bytes.Buffer
+	cli.PrintUsage(&out)
+	if !strings.Contains(out.String(), "-v: Verbosity level (default: 4, repeatable)") {
+		t.Errorf("Expected count flag in help, got:\n%s", out.String())
+	}
+
+	// Count flags take no value in completions and may be repeated in zsh.
+	out.Reset()
+	cli.GenBashCompletion(&out)
+	if strings.Contains(out.String(), "--verbose|") {
+		t.Errorf("Expected count flag to take no value in bash completions, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	cli.GenZshCompletion(&out)
+	if !strings.Contains(out.String(), "'*--verbose[Verbosity level]'") {
+		t.Errorf("Expected repeatable count flag in zsh completions, got:\n%s", out.String())
+	}
+}
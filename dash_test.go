@@ -0,0 +1,88 @@
+package goflag
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDashValues(t *testing.T) {
+	var (
+		offset        int
+		delta         float64
+		shift         time.Duration
+		ids           []int
+		input, output string
+		pattern       string
+		verbose       bool
+		name          string
+	)
+
+	cli := New()
+	cli.Int("offset", "o", &offset, "Offset")
+	cli.Float64("delta", "d", &delta, "Delta")
+	cli.Duration("shift", "s", &shift, "Shift")
+	cli.IntSlice("ids", "i", &ids, "IDs")
+	cli.FilePath("input", "f", &input, "Input file")
+	cli.String("output", "O", &output, "Output")
+	cli.String("pattern", "p", &pattern, "Pattern").ConsumeNext()
+	cli.Bool("verbose", "v", &verbose, "Verbose")
+	cli.String("name", "n", &name, "Name")
+
+	argv := []string{
+		"app", "--offset", "-5", "-d", "-1.5", "--shift", "-2s", "-i", "-1,-2",
+		"--input", "-", "-O", "-", "--pattern", "--tmp", "-v",
+	}
+	if _, err := cli.Parse(argv); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if offset != -5 || delta != -1.5 || shift != -2*time.Second || len(ids) != 2 || ids[1] != -2 {
+		t.Errorf("Expected negative numbers, got %d, %v, %v and %v", offset, delta, shift, ids)
+	}
+
+	if input != "-" || output != "-" || pattern != "--tmp" || !verbose {
+		t.Errorf("Expected input -, output -, pattern --tmp and verbose, got %q, %q, %q and %v", input, output, pattern, verbose)
+	}
+
+	// Values given with = and in clusters are taken as is.
+	if _, err := cli.Parse([]string{"app", "--offset=-7", "-vo", "-8"}); err != nil || offset != -8 {
+		t.Errorf("Expected offset -8, got %d (%v)", offset, err)
+	}
+
+	if _, err := cli.Parse([]string{"app", "--name=-x"}); err != nil || name != "-x" {
+		t.Errorf("Expected name -x, got %q (%v)", name, err)
+	}
+
+	// Other values starting with a dash are flags.
+	for _, argv := range [][]string{
+		{"app", "--name", "-v"},
+		{"app", "--name", "-x"},
+		{"app", "--offset", "-v"},
+		{"app", "--offset", "-inf"},
+		{"app", "--offset", "-1e3"},
+		{"app", "-i", "-1.5"},
+		{"app", "--name", "--"},
+	} {
+		if _, err := cli.Parse(argv); err == nil || strings.Contains(err.Error(), "invalid int value") {
+			t.Errorf("%v: expected missing value or unknown flag error, got %v", argv, err)
+		}
+	}
+
+	// Float flags still take float notations.
+	if _, err := cli.Parse([]string{"app", "-d", "-1e3"}); err != nil || delta != -1e3 {
+		t.Errorf("Expected delta -1e3, got %v (%v)", delta, err)
+	}
+
+	// A real file path is still resolved.
+	path := filepath.Join(t.TempDir(), "in.txt")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cli.Parse([]string{"app", "-f", path}); err != nil || input != path {
+		t.Errorf("Expected input %s, got %q (%v)", path, input, err)
+	}
+}
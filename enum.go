@@ -0,0 +1,67 @@
+package goflag
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Enum restricts the values of the flag or argument to the given values.
+// Each element of a slice flag and each value of a map flag is validated.
+//
+// The values are shown in the help as {a|b|c} and suggested by the
+// completion scripts.
+func (flag *Flag) Enum(values ...string) *Flag {
+	flag.enum = values
+	if _, isMap := mapElemType[flag.flagType]; isMap {
+		flag.valueValidators = append(flag.valueValidators, enumValidator(values))
+	} else {
+		flag.validators = append(flag.validators, enumValidator(values))
+	}
+	return flag
+}
+
+// Restrict the values of the last flag or argument in the subcommand chain.
+// See Flag.Enum.
+func (cmd *subcommand) Enum(values ...string) *subcommand {
+	if flag := cmd.lastFlag(); flag != nil {
+		flag.Enum(values...)
+	}
+	return cmd
+}
+
+// Returns a validator that checks that the value, or each element of a
+// slice, formats to one of values.
+func enumValidator(values []string) FlagValidator {
+	return func(v any) (bool, string) {
+		elems := []any{v}
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Slice {
+			elems = elems[:0]
+			for i := range rv.Len() {
+				elems = append(elems, rv.Index(i).Interface())
+			}
+		}
+
+		for _, elem := range elems {
+			if !slices.Contains(values, fmt.Sprint(elem)) {
+				return false, fmt.Sprintf("Expected value to be one of: %v", values)
+			}
+		}
+		return true, ""
+	}
+}
+
+// Returns the usage of the flag followed by its enumerated values, if any.
+// e.g Output format {json|yaml}
+func (flag *Flag) usageText() string {
+	if len(flag.enum) == 0 {
+		return flag.usage
+	}
+
+	values := "{" + strings.Join(flag.enum, "|") + "}"
+	if flag.usage == "" {
+		return values
+	}
+	return flag.usage + " " + values
+}
@@ -0,0 +1,74 @@
+package goflag
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestEnum(t *testing.T) {
+	var (
+		format string
+		level  int
+		tags   []string
+		env    map[string]string
+		mode   string
+	)
+
+	newCLI := func() *CLI {
+		cli := New()
+		cli.String("format", "f", &format, "Output format").Enum("json", "yaml")
+		cli.Int("level", "l", &level, "Level").Enum("1", "2", "3")
+		cli.StringSlice("tags", "t", &tags, "Tags").Enum("a", "b")
+		cli.StringMap("env", "e", &env, "Environments").Enum("dev", "prod")
+		cli.Arg("mode", &mode, "").Enum("fast", "slow")
+		return cli
+	}
+
+	if _, err := newCLI().Parse([]string{"app", "-f", "yaml", "-l", "2", "-t", "a,b", "-e", "x=prod", "fast"}); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if format != "yaml" || level != 2 || len(tags) != 2 || env["x"] != "prod" || mode != "fast" {
+		t.Errorf("Unexpected values %q, %d, %v, %v and %q", format, level, tags, env, mode)
+	}
+
+	invalid := []struct {
+		argv []string
+		want string
+	}{
+		{[]string{"app", "-f", "xml"}, "invalid value (xml) for flag [--format]: Expected value to be one of: [json yaml]"},
+		{[]string{"app", "-l", "4"}, "invalid value (4) for flag [--level]: Expected value to be one of: [1 2 3]"},
+		{[]string{"app", "-t", "a,c"}, "invalid value ([a c]) for flag [--tags]: Expected value to be one of: [a b]"},
+		{[]string{"app", "-e", "x=qa"}, "key x: invalid value (qa): Expected value to be one of: [dev prod]"},
+		{[]string{"app", "medium"}, "invalid value (medium) for argument <mode>: Expected value to be one of: [fast slow]"},
+	}
+
+	for _, test := range invalid {
+		_, err := newCLI().Parse(test.argv)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%v: expected error %q, but got %v", test.argv, test.want, err)
+		}
+	}
+
+	cli := newCLI()
+	var out bytes.Buffer
+	cli.PrintUsage(&out)
+	for _, want := range []string{"-f: Output format {json|yaml} (default:", "mode  {fast|slow}"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected help to contain %q, got:\n%s", want, out.String())
+		}
+	}
+
+	candidates, directive := cli.complete(context.Background(), []string{"--format", "y"})
+	if !slices.Equal(candidates, []string{"yaml"}) || directive != CompletionNoFile {
+		t.Errorf("Expected yaml, but got %q and %d", candidates, directive)
+	}
+
+	// The completion command validates the shell.
+	if _, err := New().Parse([]string{"app", "completion", "-s", "tcsh"}); err == nil {
+		t.Errorf("Expected error for an unsupported shell")
+	}
+}
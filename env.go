@@ -0,0 +1,72 @@
+package goflag
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// AutoEnv derives an environment variable name for every flag without an
+// explicit Flag.Env. The name is the prefix, the subcommand path and the
+// flag name joined by underscores in upper case, with dashes replaced.
+// e.g with prefix "MYAPP", --name of the greet subcommand reads MYAPP_GREET_NAME
+// and the global --dry-run flag reads MYAPP_DRY_RUN.
+//
+// Values are taken from the environment only if the flag is not given on the
+// command line. They are parsed and validated like command line values.
+func (c *CLI) AutoEnv(prefix string) *CLI {
+	c.envPrefix = prefix
+	return c
+}
+
+// Returns the environment variable of a flag defined by cmd or
+// an empty string if it has none. cmd is nil for global flags.
+func (c *CLI) envName(flag *Flag, cmd *subcommand) string {
+	if flag.env != "" {
+		return flag.env
+	}
+
+	if c == nil || c.envPrefix == "" || flag.positional || isHelpFlag(flag.name) {
+		return ""
+	}
+
+	parts := []string{c.envPrefix}
+	if cmd != nil {
+		parts = append(parts, strings.Fields(cmd.Path())...)
+	}
+	parts = append(parts, flag.name)
+
+	name := strings.ToUpper(strings.Join(parts, "_"))
+	return strings.NewReplacer("-", "_", ".", "_").Replace(name)
+}
+
+// Set the flags that were not given on the command line from their
+// environment variables. Empty variables are ignored.
+// cmd is the subcommand that defines the flags, nil for global flags.
+func (c *CLI) applyEnv(flags []*Flag, cmd *subcommand) error {
+	for _, flag := range flags {
+		if flag.Changed() || flag.value == nil {
+			continue
+		}
+
+		name := c.envName(flag, cmd)
+		if name == "" {
+			continue
+		}
+
+		value, ok := os.LookupEnv(name)
+		if !ok || value == "" {
+			continue
+		}
+
+		if err := parseFlagValue(flag, value); err != nil {
+			return fmt.Errorf("invalid value for flag [--%s] from environment variable %s: %w", flag.name, name, err)
+		}
+
+		if err := validateFlagValue(flag); err != nil {
+			return fmt.Errorf("environment variable %s: %w", name, err)
+		}
+		flag.source = SourceEnv
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package goflag
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnvFallback(t *testing.T) {
+	var (
+		port    int
+		timeout time.Duration
+		name    string
+		steps   int
+	)
+
+	newCLI := func() *CLI {
+		port, timeout, name, steps = 8080, time.Second, "World", 0
+		cli := New().AutoEnv("MYAPP")
+		cli.Int("port", "p", &port, "Port").Env("APP_PORT")
+		cli.Duration("read-timeout", "t", &timeout, "Read timeout")
+		cli.SubCommand("greet", "Greet a person", func() {}).
+			String("name", "n", &name, "Name").Required().
+			SubCommand("many", "Greet many times", func() {}).
+			Int("steps", "s", &steps, "Steps").Env("STEPS").Validate(Min(1))
+		return cli
+	}
+
+	t.Setenv("APP_PORT", "9090")
+	t.Setenv("MYAPP_PORT", "1111") // explicit Env takes precedence over AutoEnv.
+	t.Setenv("MYAPP_READ_TIMEOUT", "5s")
+	t.Setenv("MYAPP_GREET_NAME", "Env")
+	t.Setenv("STEPS", "3")
+
+	cli := newCLI()
+	if _, err := cli.Parse([]string{"myapp", "greet", "many"}); err != nil {
+		t.Fatalf("Expected required flag to be satisfied by env, but got '%v'", err)
+	}
+
+	if port != 9090 || timeout != 5*time.Second || name != "Env" || steps != 3 {
+		t.Errorf("Unexpected env values: port=%d timeout=%v name=%q steps=%d", port, timeout, name, steps)
+	}
+
+	// argv takes precedence over env.
+	cli = newCLI()
+	if _, err := cli.Parse([]string{"myapp", "-p", "7070", "greet", "--name", "Argv"}); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if port != 7070 || name != "Argv" {
+		t.Errorf("Expected argv to win over env, got port=%d name=%q", port, name)
+	}
+
+	// Env values are parsed and validated.
+	t.Setenv("STEPS", "0")
+	cli = newCLI()
+	if _, err := cli.Parse([]string{"myapp", "greet", "many"}); err == nil || !strings.Contains(err.Error(), "STEPS") {
+		t.Errorf("Expected validation error mentioning STEPS, but got %v", err)
+	}
+
+	t.Setenv("APP_PORT", "not-a-port")
+	cli = newCLI()
+	if _, err := cli.Parse([]string{"myapp"}); err == nil || !strings.Contains(err.Error(), "APP_PORT") {
+		t.Errorf("Expected parse error mentioning APP_PORT, but got %v", err)
+	}
+
+	// Empty variables are ignored.
+	t.Setenv("APP_PORT", "")
+	t.Setenv("MYAPP_GREET_NAME", "")
+	cli = newCLI()
+	if _, err := cli.Parse([]string{"myapp", "greet"}); err == nil {
+		t.Errorf("Expected missing required flag with empty env variable")
+	}
+
+	var buf bytes.Buffer
+	cli.PrintUsage(&buf)
+	for _, expected := range []string{"[env: APP_PORT]", "[env: MYAPP_READ_TIMEOUT]", "[env: MYAPP_GREET_NAME]", "[env: STEPS]"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected usage to contain %q, got:\n%s", expected, buf.String())
+		}
+	}
+}
@@ -85,6 +85,20 @@ func (c *CLI) Bool(name, shortName string, valuePtr *bool, usage string) *Flag {
 	return c.addFlag(flagBool, name, shortName, valuePtr, usage)
 }
 
+// Count adds a counter flag to the CLI.
+// Like boolean flags, count flags don't require a value; each occurrence
+// increments the counter. e.g -v -v -v, -vvv or --verbose=3
+// Parameters:
+//   - name: The long name of the flag
+//   - shortName: The short name of the flag, can be empty
+//   - valuePtr: Pointer to an int variable where the count will be stored
+//   - usage: Description of the flag shown in help text
+//
+// Returns the created Flag for further configuration.
+func (c *CLI) Count(name, shortName string, valuePtr *int, usage string) *Flag {
+	return c.addFlag(flagCount, name, shortName, valuePtr, usage)
+}
+
 // Rune adds a single Unicode character flag to the CLI.
 // Parameters:
 //   - name: The long name of the flag
@@ -136,6 +150,37 @@ func (c *CLI) IntSlice(name, shortName string, valuePtr *[]int, usage string) *F
 	return c.addFlag(flagIntSlice, name, shortName, valuePtr, usage)
 }
 
+// StringMap adds a map flag of key=value pairs to the CLI.
+// Pairs are comma-separated, and repeated flags accumulate into the map.
+// e.g --label env=prod --label team=core or --label env=prod,team=core
+// Parameters:
+//   - name: The long name of the flag
+//   - shortName: The short name of the flag, can be empty
+//   - valuePtr: Pointer to a map[string]string variable where the parsed pairs will be stored
+//   - usage: Description of the flag shown in help text
+//
+// Returns the created Flag for further configuration.
+func (c *CLI) StringMap(name, shortName string, valuePtr *map[string]string, usage string) *Flag {
+	return c.addFlag(flagStringMap, name, shortName, valuePtr, usage)
+}
+
+// IntMap adds a map flag of key=int pairs to the CLI. e.g --set a=1,b=2
+// See CLI.StringMap for parameter details.
+//
+// Returns the created Flag for further configuration.
+func (c *CLI) IntMap(name, shortName string, valuePtr *map[string]int, usage string) *Flag {
+	return c.addFlag(flagIntMap, name, shortName, valuePtr, usage)
+}
+
+// DurationMap adds a map flag of key=duration pairs to the CLI.
+// e.g --timeout read=5s,write=10s
+// See CLI.StringMap for parameter details.
+//
+// Returns the created Flag for further configuration.
+func (c *CLI) DurationMap(name, shortName string, valuePtr *map[string]time.Duration, usage string) *Flag {
+	return c.addFlag(flagDurationMap, name, shortName, valuePtr, usage)
+}
+
 // Time adds a time.Time flag to the CLI.
 // Accepts various time formats for parsing.
 // Parameters:
@@ -253,6 +298,18 @@ func (c *CLI) DirPath(name, shortName string, valuePtr *string, usage string) *F
 	return c.addFlag(flagDirPath, name, shortName, valuePtr, usage)
 }
 
+// Var adds a flag with a custom Value to the CLI.
+// Parameters:
+//   - name: The long name of the flag
+//   - shortName: The short name of the flag, can be empty
+//   - value: The Value that parses and stores the flag value
+//   - usage: Description of the flag shown in help text
+//
+// Returns the created Flag for further configuration.
+func (c *CLI) Var(name, shortName string, value Value, usage string) *Flag {
+	return c.addFlag(flagCustom, name, shortName, value, usage)
+}
+
 // Helper methods on subcommand for defining flags with specific types.
 // These mirror the CLI-level helpers but return *subcommand for method chaining.
 
@@ -291,6 +348,13 @@ func (cmd *subcommand) Float64(name, shortName string, valuePtr *float64, usage
 	return cmd.Flag(flagFloat64, name, shortName, valuePtr, usage)
 }
 
+// Count adds a counter flag to the subcommand.
+// See CLI.Count for parameter details.
+// Returns the subcommand for method chaining.
+func (cmd *subcommand) Count(name, shortName string, valuePtr *int, usage string) *subcommand {
+	return cmd.Flag(flagCount, name, shortName, valuePtr, usage)
+}
+
 // Bool adds a boolean flag to the subcommand.
 // See CLI.Bool for parameter details.
 // Returns the subcommand for method chaining.
@@ -326,6 +390,27 @@ func (cmd *subcommand) IntSlice(name, shortName string, valuePtr *[]int, usage s
 	return cmd.Flag(flagIntSlice, name, shortName, valuePtr, usage)
 }
 
+// StringMap adds a map flag of key=value pairs to the subcommand.
+// See CLI.StringMap for parameter details.
+// Returns the subcommand for method chaining.
+func (cmd *subcommand) StringMap(name, shortName string, valuePtr *map[string]string, usage string) *subcommand {
+	return cmd.Flag(flagStringMap, name, shortName, valuePtr, usage)
+}
+
+// IntMap adds a map flag of key=int pairs to the subcommand.
+// See CLI.StringMap for parameter details.
+// Returns the subcommand for method chaining.
+func (cmd *subcommand) IntMap(name, shortName string, valuePtr *map[string]int, usage string) *subcommand {
+	return cmd.Flag(flagIntMap, name, shortName, valuePtr, usage)
+}
+
+// DurationMap adds a map flag of key=duration pairs to the subcommand.
+// See CLI.StringMap for parameter details.
+// Returns the subcommand for method chaining.
+func (cmd *subcommand) DurationMap(name, shortName string, valuePtr *map[string]time.Duration, usage string) *subcommand {
+	return cmd.Flag(flagDurationMap, name, shortName, valuePtr, usage)
+}
+
 // Time adds a time.Time flag to the subcommand.
 // See CLI.Time for parameter details.
 // Returns the subcommand for method chaining.
@@ -337,7 +422,7 @@ func (cmd *subcommand) Time(name, shortName string, valuePtr *time.Time, usage s
 // See CLI.IP for parameter details.
 // Returns the subcommand for method chaining.
 func (cmd *subcommand) IP(name, shortName string, valuePtr *net.IP, usage string) *subcommand {
-	return cmd.Flag(flagFloat32, name, shortName, valuePtr, usage) // BUG: Should be FlagIP
+	return cmd.Flag(flagIP, name, shortName, valuePtr, usage)
 }
 
 // MAC adds a MAC address flag to the subcommand.
@@ -388,3 +473,10 @@ func (cmd *subcommand) FilePath(name, shortName string, valuePtr *string, usage
 func (cmd *subcommand) DirPath(name, shortName string, valuePtr *string, usage string) *subcommand {
 	return cmd.Flag(flagDirPath, name, shortName, valuePtr, usage)
 }
+
+// Var adds a flag with a custom Value to the subcommand.
+// See CLI.Var for parameter details.
+// Returns the subcommand for method chaining.
+func (cmd *subcommand) Var(name, shortName string, value Value, usage string) *subcommand {
+	return cmd.Flag(flagCustom, name, shortName, value, usage)
+}
@@ -27,11 +27,17 @@ func _() {
 	_ = x[flagEmail-16]
 	_ = x[flagFilePath-17]
 	_ = x[flagDirPath-18]
+	_ = x[flagCustom-19]
+	_ = x[flagRegistered-20]
+	_ = x[flagCount-21]
+	_ = x[flagStringMap-22]
+	_ = x[flagIntMap-23]
+	_ = x[flagDurationMap-24]
 }
 
-const _flagType_name = "FlagStringFlagIntFlagInt64FlagFloat32FlagFloat64FlagBoolFlagRuneFlagDurationFlagStringSliceFlagIntSliceFlagTimeFlagIPFlagMACFlagURLFlagUUIDFlagHostPortPairFlagEmailFlagFilePathFlagDirPath"
+const _flagType_name = "FlagStringFlagIntFlagInt64FlagFloat32FlagFloat64FlagBoolFlagRuneFlagDurationFlagStringSliceFlagIntSliceFlagTimeFlagIPFlagMACFlagURLFlagUUIDFlagHostPortPairFlagEmailFlagFilePathFlagDirPathFlagCustomFlagRegisteredFlagCountFlagStringMapFlagIntMapFlagDurationMap"
 
-var _flagType_index = [...]uint8{0, 10, 17, 26, 37, 48, 56, 64, 76, 91, 103, 111, 117, 124, 131, 139, 155, 164, 176, 187}
+var _flagType_index = [...]uint16{0, 10, 17, 26, 37, 48, 56, 64, 76, 91, 103, 111, 117, 124, 131, 139, 155, 164, 176, 187, 197, 211, 220, 233, 243, 258}
 
 func (i flagType) String() string {
 	idx := int(i) - 0
@@ -0,0 +1,177 @@
+package goflag
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FlagSet is implemented by *CLI and *Command. It is the target of Add.
+type FlagSet interface {
+	addFlag(flagType flagType, name, shortName string, valuePtr any, usage string) *Flag
+}
+
+// Option configures a flag of type T created by Add.
+type Option[T any] func(flag *Flag)
+
+// Check returns an Option that validates the parsed value of a flag.
+// The validator is typed, so a mismatch with the flag type is a compile error.
+// e.g goflag.Add(cli, "port", "p", &port, "Port", goflag.Check(func(v int) error {...}))
+func Check[T any](validate func(value T) error) Option[T] {
+	return func(flag *Flag) {
+		flag.validators = append(flag.validators, func(v any) (bool, string) {
+			var value T
+			switch v := v.(type) {
+			case T:
+				value = v
+			case *T:
+				value = *v // custom flags pass their Value.
+			default:
+				return false, fmt.Sprintf("invalid type %T for flag %s", v, flag.name)
+			}
+
+			if err := validate(value); err != nil {
+				return false, err.Error()
+			}
+			return true, ""
+		})
+	}
+}
+
+// Add creates a flag of type T on a CLI or subcommand and returns it.
+// T is one of the built-in types (string, int, time.Duration, net.IP ...),
+// a type whose pointer implements Value, or a type registered with RegisterParser.
+// It panics if T is not supported.
+//
+//	var port int
+//	goflag.Add(cli, "port", "p", &port, "Port to listen on").Required()
+func Add[T any](set FlagSet, name, shortName string, valuePtr *T, usage string, opts ...Option[T]) *Flag {
+	flagType, ok := flagTypeOf(valuePtr)
+	if !ok {
+		panic(fmt.Errorf("unsupported type %T for flag %s: implement Value or use RegisterParser", valuePtr, name))
+	}
+
+	flag := set.addFlag(flagType, name, shortName, valuePtr, usage)
+	for _, opt := range opts {
+		opt(flag)
+	}
+	return flag
+}
+
+// Flag types of the built-in value types.
+// Types with more than one flag type, like string for FilePath, map to the plain one.
+var builtinFlagTypes = map[reflect.Type]flagType{
+	reflect.TypeFor[string]():                   flagString,
+	reflect.TypeFor[int]():                      flagInt,
+	reflect.TypeFor[int64]():                    flagInt64,
+	reflect.TypeFor[float32]():                  flagFloat32,
+	reflect.TypeFor[float64]():                  flagFloat64,
+	reflect.TypeFor[bool]():                     flagBool,
+	reflect.TypeFor[rune]():                     flagRune,
+	reflect.TypeFor[time.Duration]():            flagDuration,
+	reflect.TypeFor[[]string]():                 flagStringSlice,
+	reflect.TypeFor[[]int]():                    flagIntSlice,
+	reflect.TypeFor[map[string]string]():        flagStringMap,
+	reflect.TypeFor[map[string]int]():           flagIntMap,
+	reflect.TypeFor[map[string]time.Duration](): flagDurationMap,
+	reflect.TypeFor[time.Time]():                flagTime,
+	reflect.TypeFor[net.IP]():                   flagIP,
+	reflect.TypeFor[net.HardwareAddr]():         flagMAC,
+	reflect.TypeFor[url.URL]():                  flagURL,
+	reflect.TypeFor[uuid.UUID]():                flagUUID,
+}
+
+// Infer the flag type from a value pointer.
+func flagTypeOf(valuePtr any) (flagType, bool) {
+	if _, ok := valuePtr.(Value); ok {
+		return flagCustom, true
+	}
+
+	ptrType := reflect.TypeOf(valuePtr)
+	if ptrType == nil || ptrType.Kind() != reflect.Pointer {
+		return 0, false
+	}
+
+	if flagType, ok := builtinFlagTypes[ptrType.Elem()]; ok {
+		return flagType, true
+	}
+
+	if _, ok := lookupParser(ptrType.Elem()); ok {
+		return flagRegistered, true
+	}
+	return 0, false
+}
+
+// A parser registered with RegisterParser.
+type typeParser struct {
+	name  string
+	parse func(value string) (any, error)
+}
+
+var (
+	parsersMu sync.RWMutex
+	parsers   = make(map[reflect.Type]typeParser)
+)
+
+// RegisterParser registers the parser of type T so that it can be used
+// with Add and Arg without implementing Value. name is the type shown in
+// shell completions. Registering a type again replaces its parser.
+// It is safe to call concurrently, typically from an init function.
+//
+//	goflag.RegisterParser("semver", semver.NewVersion)
+func RegisterParser[T any](name string, parse func(value string) (T, error)) {
+	parsersMu.Lock()
+	defer parsersMu.Unlock()
+
+	parsers[reflect.TypeFor[T]()] = typeParser{
+		name: name,
+		parse: func(value string) (any, error) {
+			return parse(value)
+		},
+	}
+}
+
+func lookupParser(t reflect.Type) (typeParser, bool) {
+	parsersMu.RLock()
+	defer parsersMu.RUnlock()
+
+	parser, ok := parsers[t]
+	return parser, ok
+}
+
+// The Value of a flag whose type has a registered parser.
+type parsedValue struct {
+	ptr    reflect.Value // pointer to the value.
+	parser typeParser
+}
+
+func (v *parsedValue) Set(value string) error {
+	result, err := v.parser.parse(value)
+	if err != nil {
+		return err
+	}
+	v.ptr.Elem().Set(reflect.ValueOf(result))
+	return nil
+}
+
+func (v *parsedValue) String() string { return fmt.Sprint(v.ptr.Elem().Interface()) }
+func (v *parsedValue) Type() string   { return v.parser.name }
+
+// Returns the Value of a registered type bound to valuePtr or nil.
+func newParsedValue(valuePtr any) Value {
+	ptr := reflect.ValueOf(valuePtr)
+	if ptr.Kind() != reflect.Pointer || ptr.IsNil() {
+		return nil
+	}
+
+	parser, ok := lookupParser(ptr.Type().Elem())
+	if !ok {
+		return nil
+	}
+	return &parsedValue{ptr: ptr, parser: parser}
+}
@@ -0,0 +1,130 @@
+package goflag
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A type without a Value implementation, parsed by a registered parser.
+type version struct {
+	major, minor int
+}
+
+func (v version) String() string {
+	return fmt.Sprintf("v%d.%d", v.major, v.minor)
+}
+
+func parseVersion(value string) (version, error) {
+	var v version
+	if _, err := fmt.Sscanf(value, "v%d.%d", &v.major, &v.minor); err != nil {
+		return v, fmt.Errorf("invalid version %s", value)
+	}
+	return v, nil
+}
+
+func init() {
+	RegisterParser("version", parseVersion)
+}
+
+func TestAdd(t *testing.T) {
+	var (
+		port       = 8080
+		timeout    time.Duration
+		ip         net.IP
+		home       = region("us-east-1")
+		minVersion = version{1, 0}
+		target     version
+	)
+
+	cli := New()
+	Add(cli, "port", "p", &port, "Port", Check(func(v int) error {
+		if v < 1024 {
+			return errors.New("port must be unprivileged")
+		}
+		return nil
+	})).Required()
+	Add(cli, "timeout", "t", &timeout, "Timeout")
+	Add(cli, "region", "r", &home, "Region", Check(func(v region) error {
+		if v == "eu-west-1" {
+			return errors.New("region is full")
+		}
+		return nil
+	}))
+	Add(cli, "min", "m", &minVersion, "Minimum version")
+
+	cmd := cli.SubCommand("deploy", "Deploy", func() {})
+	Add(cmd, "ip", "i", &ip, "IP")
+	Add(cmd, "target", "T", &target, "Target version", Check(func(v version) error {
+		if v.major < minVersion.major {
+			return errors.New("target is older than minimum")
+		}
+		return nil
+	}))
+
+	_, err := cli.Parse([]string{"app", "-p", "9000", "-t", "2s", "--min", "v2.1", "deploy", "-i", "10.0.0.1", "-T", "v3.0"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if port != 9000 || timeout != 2*time.Second || minVersion != (version{2, 1}) {
+		t.Errorf("Unexpected values: port=%d timeout=%v min=%v", port, timeout, minVersion)
+	}
+
+	if !ip.Equal(net.ParseIP("10.0.0.1")) || target != (version{3, 0}) {
+		t.Errorf("Unexpected subcommand values: ip=%v target=%v", ip, target)
+	}
+
+	tests := []struct {
+		argv []string
+		want string
+	}{
+		{[]string{"app", "-p", "80"}, "port must be unprivileged"},
+		{[]string{"app", "-p", "9000", "-r", "eu-west-1"}, "region is full"},
+		{[]string{"app", "-p", "9000", "--min", "1.0"}, "invalid version 1.0"},
+		{[]string{"app", "-p", "9000", "deploy", "-T", "v1.0"}, "target is older than minimum"},
+	}
+
+	for _, test := range tests {
+		minVersion = version{2, 0}
+		_, err := cli.Parse(test.argv)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%v: expected error containing %q, but got %v", test.argv, test.want, err)
+		}
+	}
+
+	// Registered types show their name in completions.
+	var out strings.Builder
+	cli.GenZshCompletion(&out)
+	if !strings.Contains(out.String(), "'--min[Minimum version]:version:'") {
+		t.Errorf("Expected version placeholder in zsh completion, got:\n%s", out.String())
+	}
+}
+
+func TestAddUnsupportedType(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "unsupported type *complex128") {
+			t.Errorf("Expected panic for unsupported type, but got %v", r)
+		}
+	}()
+
+	var c complex128
+	Add(New(), "c", "", &c, "Complex")
+}
+
+func TestRegisteredArg(t *testing.T) {
+	var v version
+	cli := New()
+	cli.Arg("version", &v, "Version").Required()
+
+	if _, err := cli.Parse([]string{"app", "v1.2"}); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if v != (version{1, 2}) {
+		t.Errorf("Expected v1.2, but got %v", v)
+	}
+}
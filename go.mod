@@ -2,7 +2,11 @@ module github.com/abiiranathan/goflag
 
 go 1.25.0
 
-require github.com/google/uuid v1.5.0
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/google/uuid v1.5.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	golang.org/x/mod v0.31.0 // indirect
@@ -7,12 +7,17 @@
 package goflag
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"os"
+	"path/filepath"
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
 //go:generate go tool stringer -type flagType
@@ -39,6 +44,12 @@ const (
 	flagEmail
 	flagFilePath
 	flagDirPath
+	flagCustom     // a user-defined Value. See CLI.Var.
+	flagRegistered // a type with a parser registered by RegisterParser.
+	flagCount      // an int incremented on each occurrence. e.g -vvv
+	flagStringMap
+	flagIntMap
+	flagDurationMap
 )
 
 type FlagValidator func(value any) (valid bool, errmsg string)
@@ -52,6 +63,25 @@ type Flag struct {
 	usage      string
 	required   bool
 	validators []FlagValidator
+	env        string // environment variable used if the flag is not given.
+	positional bool   // A positional argument rather than a flag.
+	variadic   bool   // A positional argument that collects all remaining tokens.
+	configFile bool   // The flag holds the path of the configuration file.
+	source     Source // where the value came from in the last Parse.
+
+	appendValues bool // repeated values of a slice flag accumulate. See Flag.Append.
+	separator    rune // separator of the elements of a slice flag, ',' if 0.
+	noSplit      bool // each value of a slice flag is a single element.
+
+	negatable   bool // a bool flag that may be set to false with --no-<name>.
+	consumeNext bool // the next arg is always the value, even if it starts with a dash.
+
+	keyPolicy       DuplicateKeyPolicy // how repeated keys of a map flag are handled.
+	valueValidators []FlagValidator    // validators called with each value of a map flag.
+
+	completeFunc CompletionFunc // completes values at runtime. See Flag.Complete.
+	extensions   []string       // file extensions completed for the value. See Flag.CompleteFiles.
+	enum         []string       // the allowed values. See Flag.Enum.
 }
 
 // Add validator to last flag in the subcommand chain. If no flag exists, it panics.
@@ -65,14 +95,162 @@ func (flag *Flag) Required() *Flag {
 	return flag
 }
 
+// Env sets the environment variable used as the value of the flag if it is
+// not given on the command line. Takes precedence over names derived by AutoEnv.
+func (flag *Flag) Env(name string) *Flag {
+	flag.env = name
+	return flag
+}
+
+// Negatable allows a bool flag to be set to false with --no-<name>.
+// e.g --no-color for a color flag that defaults to true.
+// The help shows the flag as --[no-]<name>.
+// It panics if the flag is not a bool flag.
+func (flag *Flag) Negatable() *Flag {
+	if !flag.isBool() {
+		panic(fmt.Errorf("flag %s must be a bool flag to be negatable", flag.name))
+	}
+	flag.negatable = true
+	return flag
+}
+
+// ConsumeNext makes the flag always take the next arg as its value,
+// even if it looks like a flag. e.g --exclude --tmp
+func (flag *Flag) ConsumeNext() *Flag {
+	flag.consumeNext = true
+	return flag
+}
+
+// Reports whether next, the arg after a flag, may be the value of the flag.
+// Args starting with a dash are flags, except negative numbers for numeric
+// flags, a lone - for string and file flags, and any arg for flags marked
+// with ConsumeNext.
+func (flag *Flag) acceptsValue(next string) bool {
+	if flag.consumeNext || next == "" || next[0] != '-' {
+		return true
+	}
+
+	switch flag.flagType {
+	case flagInt, flagInt64, flagIntSlice:
+		elem, _, _ := strings.Cut(next, ",")
+		_, err := strconv.ParseInt(strings.TrimSpace(elem), 10, 64)
+		return err == nil
+	case flagFloat32, flagFloat64:
+		_, err := strconv.ParseFloat(next, 64)
+		return err == nil
+	case flagDuration:
+		_, err := time.ParseDuration(next)
+		return err == nil
+	case flagString, flagFilePath:
+		return next == "-"
+	}
+	return false
+}
+
+// Returns the name of the flag shown in the help. e.g [no-]color
+func (flag *Flag) displayName() string {
+	if flag.negatable {
+		return "[no-]" + flag.name
+	}
+	return flag.name
+}
+
+// Find the negatable bool flag negated by name. e.g color for no-color
+func findNegatedFlag(flags []*Flag, name string) *Flag {
+	name, ok := strings.CutPrefix(name, "no-")
+	if !ok {
+		return nil
+	}
+
+	for _, flag := range flags {
+		if flag.name == name && flag.negatable {
+			return flag
+		}
+	}
+	return nil
+}
+
+// Value returns the Value of the flag. Flags of built-in types
+// return a Value bound to their value pointer. The Set of slice and
+// map flags parses like the command line, with the options of the flag.
+func (flag *Flag) Value() Value {
+	if flag.value == nil {
+		return nil
+	}
+
+	if v := newListValue(flag); v != nil {
+		return v
+	}
+	return newValue(flag.flagType, flag.value)
+}
+
+// Reports whether the flag is a bool flag that takes no value.
+func (flag *Flag) isBool() bool {
+	if flag.flagType == flagBool {
+		return true
+	}
+
+	if flag.flagType == flagCustom {
+		b, ok := flag.value.(boolFlag)
+		return ok && b.IsBoolFlag()
+	}
+	return false
+}
+
+// Reports whether the flag takes a value on the command line.
+// Bool and count flags don't, unless given with =. The help flag never does.
+func (flag *Flag) takesValue() bool {
+	return !flag.isBool() && flag.flagType != flagCount && !isHelpFlag(flag.name)
+}
+
+// Increment a count flag given on the command line.
+// The first occurrence in a Parse replaces the default.
+func (flag *Flag) increment() {
+	count := flag.value.(*int)
+	if !flag.Changed() {
+		*count = 0
+	}
+	*count++
+	flag.source = SourceArgv
+}
+
+// Returns the current value of the flag passed to validators.
+// Built-in types are dereferenced, custom flags pass their Value.
+func (flag *Flag) current() any {
+	if flag.flagType == flagCustom {
+		return flag.value
+	}
+	return reflect.ValueOf(flag.value).Elem().Interface()
+}
+
 // Global flag context. Stores global flags and subcommands.
+// All state is kept per CLI, so independent CLIs may be built and
+// parsed concurrently. A single CLI must not be parsed concurrently.
 type CLI struct {
-	flags       []*Flag
-	subcommands []*subcommand
+	flags          []*Flag
+	args           []*Flag // positional arguments.
+	subcommands    []*subcommand
+	operands       []string                 // positional tokens from the last Parse.
+	rest           []string                 // tokens after the -- terminator from the last Parse.
+	out            io.Writer                // writer for help and completion scripts.
+	errOut         io.Writer                // writer for errors and warnings.
+	exitFunc       func(int)                // function used to exit the process.
+	envPrefix      string                   // prefix of derived environment variable names. See AutoEnv.
+	configPath     string                   // path of the configuration file. See ConfigFile.
+	configDecoders map[string]ConfigDecoder // decoders by file extension. See ConfigDecoder.
+	name           string                   // name of the program in completion scripts. See SetName.
+	constraints    []constraint
+
+	// The completion subcommand. Required global flags are not enforced for it.
+	completionCmd *subcommand
+
+	// The hidden __complete command. Its args are not parsed.
+	completeCmd *subcommand
 }
 
-// The completion subcommand.
-var completionCmd *subcommand
+// ErrHelpRequested is returned by Parse and Run when the help flag is given.
+// The usage has already been printed to the output writer of the CLI.
+var ErrHelpRequested = errors.New("goflag: help requested")
 
 // Create a new command-line interface.
 func New() *CLI {
@@ -86,39 +264,115 @@ func New() *CLI {
 	var shell string
 	var install bool
 	var uninstall bool
+	var dynamic bool
+	var system bool
+	var dryRun bool
 
-	completionCmd = cli.SubCommand("completion", "Generate shell completion scripts", func() {
-		// Check for conflicting flags
-		if install && uninstall {
-			log.Fatal("Error: cannot use --install and --uninstall together\n")
-		}
-
+	cli.completionCmd = cli.Command("completion", "Generate shell completion scripts", func(ctx context.Context, cmd *Command) error {
+		opts := InstallOptions{System: system, DryRun: dryRun, Dynamic: dynamic}
 		if uninstall {
 			// Uninstall the completion script
-			if err := cli.UninstallCompletion(shell); err != nil {
-				log.Fatalf("Failed to uninstall completion: %v\n", err)
+			if err := cli.UninstallCompletionWithOptions(shell, opts); err != nil {
+				return fmt.Errorf("failed to uninstall completion: %w", err)
 			}
-		} else if install {
-			cli.InstallCompletion(shell)
-		} else {
-			// Just print to stdout
-			switch shell {
-			case "bash":
-				cli.GenBashCompletion(os.Stdout)
-			case "zsh":
-				cli.GenZshCompletion(os.Stdout)
-			default:
-				log.Fatalf("Unsupported shell: %s\n", shell)
+			return nil
+		}
+
+		if install {
+			if err := cli.InstallCompletionWithOptions(shell, opts); err != nil {
+				return fmt.Errorf("failed to install completion: %w", err)
 			}
+			return nil
 		}
+
+		generate, err := cli.completionGenerator(shell, dynamic)
+		if err != nil {
+			return err
+		}
+
+		// Just print to the output writer
+		generate(cli.stdout())
+		return nil
 	}).
-		String("shell", "s", &shell, "The shell to generate completions for [bash|zsh]").
-		Required().Validate(Choices([]string{"zsh", "bash"})).
+		String("shell", "s", &shell, "The shell to generate completions for").
+		Required().Enum("bash", "zsh", "fish", "powershell", "nushell").
 		Bool("install", "i", &install, "Install the completion script to the appropriate location").
-		Bool("uninstall", "u", &uninstall, "Uninstall the completion script")
+		Bool("uninstall", "u", &uninstall, "Uninstall the completion script").
+		Bool("dynamic", "d", &dynamic, "Generate a script that completes values at runtime [bash|zsh]").
+		Bool("system", "", &system, "Install or uninstall the completion script system-wide").
+		Bool("dry-run", "", &dryRun, "Print what install or uninstall would change without changing it").
+		MutuallyExclusive("install", "uninstall")
+
+	// Hidden command called by the dynamic completion scripts.
+	// It is not a subcommand of the CLI, so it is not in the help or the completions.
+	cli.completeCmd = newContextSubCommand(cli, completeCmdName, "Print completion candidates", func(ctx context.Context, cmd *Command) error {
+		cli.writeCompletions(ctx, cli.stdout(), cmd.Args())
+		return nil
+	})
 	return cli
 }
 
+// SetOutput sets the writer for help messages and generated completion scripts.
+// Defaults to os.Stdout.
+func (c *CLI) SetOutput(w io.Writer) *CLI {
+	c.out = w
+	return c
+}
+
+// SetName sets the name of the program used in generated completion scripts
+// and their install paths. Defaults to the base name of os.Args[0].
+func (c *CLI) SetName(name string) *CLI {
+	c.name = name
+	return c
+}
+
+// SetErrOutput sets the writer for errors and warnings. Defaults to os.Stderr.
+func (c *CLI) SetErrOutput(w io.Writer) *CLI {
+	c.errOut = w
+	return c
+}
+
+// SetExitFunc sets the function called by Execute to exit the process.
+// Defaults to os.Exit. Embedding applications may use it to keep the process
+// running. The Handler of context-aware subcommands calls it only if it is set,
+// it never calls os.Exit.
+func (c *CLI) SetExitFunc(exit func(code int)) *CLI {
+	c.exitFunc = exit
+	return c
+}
+
+// Returns the output writer of the CLI.
+func (c *CLI) stdout() io.Writer {
+	if c == nil || c.out == nil {
+		return os.Stdout
+	}
+	return c.out
+}
+
+// Returns the name of the program in completion scripts.
+func (c *CLI) binName() string {
+	if c.name == "" {
+		return filepath.Base(os.Args[0])
+	}
+	return c.name
+}
+
+// Returns the error writer of the CLI.
+func (c *CLI) stderr() io.Writer {
+	if c == nil || c.errOut == nil {
+		return os.Stderr
+	}
+	return c.errOut
+}
+
+// Returns the exit function of the CLI.
+func (c *CLI) exitFn() func(code int) {
+	if c == nil || c.exitFunc == nil {
+		return os.Exit
+	}
+	return c.exitFunc
+}
+
 // Add a flag to the context.
 func (c *CLI) addFlag(flagType flagType, name, shortName string, valuePtr any, usage string) *Flag {
 	flag := &Flag{
@@ -134,272 +388,500 @@ func (c *CLI) addFlag(flagType flagType, name, shortName string, valuePtr any, u
 	return flag
 }
 
-// Add a subcommand to the command-line context.
-func (c *CLI) SubCommand(name, description string, handler func()) *subcommand {
-	if handler == nil {
-		panic("subcommand can not be registered with nil handler")
-	}
+// Arg adds a positional argument to the CLI.
+// The argument type is inferred from valuePtr, which must point to one of
+// the supported flag types, implement Value or point to a type registered
+// with RegisterParser. A slice argument (*[]string or *[]int) is variadic
+// and collects all remaining tokens, so it must be declared last.
+//
+// Arguments are bound in the order they are declared.
+// Returns the created argument for further configuration (e.g. Required, Validate).
+func (c *CLI) Arg(name string, valuePtr any, usage string) *Flag {
+	var arg *Flag
+	c.args, arg = addArg(c.args, name, valuePtr, usage)
+	return arg
+}
 
-	if name == "" {
-		panic("subcommand name can't be empty")
-	}
-	if description == "" {
-		panic("subcommand description can't be empty")
-	}
+// Args returns the positional arguments of the CLI from the last call to Parse,
+// including those after the -- terminator.
+func (c *CLI) Args() []string {
+	return c.operands
+}
 
-	cmd := &subcommand{
-		name:        name,
-		description: description,
-		Handler:     handler,
-		flags: []*Flag{
-			{name: "help", shortName: "h", flagType: flagString, usage: "Print help message and exit"},
-		},
-	}
+// Rest returns the arguments that followed the -- terminator in the
+// last call to Parse. They are never interpreted as flags or subcommands,
+// which makes them suitable for passing through to a child process.
+func (c *CLI) Rest() []string {
+	return c.rest
+}
+
+// Add a subcommand to the command-line context.
+func (c *CLI) SubCommand(name, description string, handler func()) *subcommand {
+	cmd := newHandlerSubCommand(c, name, description, handler)
 	c.subcommands = append(c.subcommands, cmd)
+	return cmd
+}
 
-	// add the help flag to the subcommand.
+// Command adds a subcommand with a context-aware handler.
+// The handler receives the matched command and its returned error is
+// propagated by Run and mapped to an exit code by Execute.
+func (c *CLI) Command(name, description string, handler HandlerFunc) *subcommand {
+	cmd := newContextSubCommand(c, name, description, handler)
+	c.subcommands = append(c.subcommands, cmd)
 	return cmd
 }
 
 // Parse the flags and subcommands. args should be os.Args.
 // The first argument is ignored as it is the program name.
+// If the help flag is given, the usage is printed to the output writer
+// and ErrHelpRequested is returned.
+// A "--" argument terminates flag parsing; everything after it is
+// positional and is available via Rest.
 //
-// Populates the values of the flags and also finds the matching subcommand.
-// Returns the matching subcommand.
+// Populates the values of the flags and positional arguments and also finds
+// the matching subcommand. For nested subcommands, the deepest matching
+// subcommand is returned. Use its Path method to get the full command path.
 func (c *CLI) Parse(argv []string) (*subcommand, error) {
 	var subcmd *subcommand = nil
-	subCommandIndex := -1
 
-	// store processed flags.
-	processedGlobalFlags := make(map[string]bool)
-	processedSubCommandFlags := make(map[string]bool)
+	// Forget the sources of the previous Parse.
+	c.resetSources()
 
-	if len(argv) >= 2 {
-		// skip the first argument which is the program name.
-		argv = argv[1:]
+	c.operands, c.rest = nil, nil
 
-		// First pass, consume global flags.
-	outerloop:
-		for i := 0; i < len(argv); i++ {
-			arg := argv[i]
+	// The words of the __complete command are completed, not parsed.
+	if len(argv) >= 2 && c.completeCmd != nil && argv[1] == c.completeCmd.name {
+		c.completeCmd.operands = slices.Clone(argv[2:])
+		return c.completeCmd, nil
+	}
+
+	// skip the first argument which is the program name.
+	var args []string
+	if len(argv) >= 2 {
+		args = argv[1:]
+	}
+	lex := newLexer(args)
+
+	// First pass, consume global flags.
+outerloop:
+	for {
+		tok, ok := lex.next()
+		if !ok {
+			break
+		}
 
-			if strings.TrimSpace(arg) == "" {
+		switch tok.kind {
+		case tokenTerminator:
+			continue
+		case tokenPositional:
+			if lex.terminated {
+				c.rest = append(c.rest, tok.value)
+				if err := bindRestArg(c.args, len(c.operands), tok.value); err != nil {
+					return nil, err
+				}
+				c.operands = append(c.operands, tok.value)
 				continue
 			}
 
-			// Check for = in the arg. If present, split the arg into two.
-			// The first part is the flag name and the second part is the value.
-			// e.g. --name=John
-			if strings.Contains(arg, "=") {
-				parts := strings.Split(arg, "=")       // split the arg into two.
-				arg = parts[0]                         // the first part is the flag name.
-				argv = append(argv[:i+1], argv[i:]...) // insert the second part into the argv.
-				argv[i+1] = parts[1]                   // set the second part as the next arg.
-
+			if strings.TrimSpace(tok.value) == "" {
+				continue
 			}
 
-			var name string
-
-			if arg[0] == '-' && arg[1] == '-' {
-				// long flag
-				name = arg[2:]
-			} else if arg[0] == '-' {
-				// short flag
-				name = arg[1:]
-			} else {
-				if len(c.subcommands) == 0 {
-					continue
-				}
-
-				// subcommand or flag value.
-				for _, cmd := range c.subcommands {
-					if cmd.name == arg {
-						subcmd = cmd
-						subCommandIndex = i
-						break outerloop
-					}
+			// A subcommand can only appear before any positional argument.
+			if len(c.operands) == 0 {
+				if cmd := findSubCommand(c.subcommands, tok.value); cmd != nil {
+					subcmd = cmd
+					break outerloop
 				}
-				continue // value will be consumed by looking at the next arg.
-			}
-
-			if isHelpFlag(name) {
-				c.PrintUsage(os.Stdout)
-				os.Exit(0)
 			}
 
-			flag, err := parseFlags(&c.flags, name, i, argv)
-			if err != nil {
+			if err := parseArg(c.args, len(c.operands), tok.value); err != nil {
 				return nil, err
 			}
+			c.operands = append(c.operands, tok.value)
+			continue
+		}
 
-			if flag != nil {
-				// Store the processed flag.
-				// This is used to check if all required global flags are present.
-				processedGlobalFlags[flag.name] = true
-			}
+		flags, help, err := parseFlagToken(c.flags, tok, lex)
+		if err != nil {
+			return nil, err
+		}
+
+		if help {
+			c.PrintUsage(c.stdout())
+			return nil, ErrHelpRequested
+		}
+
+		// Record the source of the flags.
+		// This is used to check if all required global flags are present.
+		for _, flag := range flags {
+			flag.source = SourceArgv
 		}
 	}
 
+	// Flags not given on the command line fall back to environment variables.
+	if err := c.applyEnv(c.flags, nil); err != nil {
+		return nil, err
+	}
+
+	// Then to the configuration file, which may itself be named by a flag.
+	config, err := c.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := config.apply(c.flags, nil); err != nil {
+		return nil, err
+	}
+
 	// check if all required global flags are present.
 	// Done after parsing the subcommand flags so that the subcommand help can be printed.
 	// if the global flags are missing.
-	if subcmd != completionCmd {
+	if subcmd == nil || subcmd != c.completionCmd {
 		for _, flag := range c.flags {
-			if _, found := processedGlobalFlags[flag.name]; !found && flag.required {
+			if !flag.Changed() && flag.required {
 				return nil, fmt.Errorf("missing required flag [-%s | --%s]", flag.shortName, flag.name)
 			}
 		}
+
+		if err := checkConstraints(c.constraints, c.flags); err != nil {
+			return nil, err
+		}
 	}
 
 	// Second pass, consume subcommand flags.
 	if subcmd == nil {
+		if err := checkRequiredArgs(c.args, len(c.operands)); err != nil {
+			return nil, err
+		}
 		return nil, nil
 	}
 
-	// remove the subcommand from the argv.
-	subCommandIndex++
+	subcmd.operands, subcmd.rest = nil, nil
+
+	// flags accepted by the current subcommand, including inherited flags.
+	flags := subcmd.allFlags()
 
 	// parse the subcommand flags.
-	for i := subCommandIndex; i < len(argv); i++ {
-		arg := argv[i]
-		if strings.TrimSpace(arg) == "" {
-			continue
+	for {
+		tok, ok := lex.next()
+		if !ok {
+			break
 		}
 
-		// Check for = in the arg. If present, split the arg into two.
-		// The first part is the flag name and the second part is the value.
-		// e.g. --name=John
-		if strings.Contains(arg, "=") {
-			parts := strings.Split(arg, "=")       // split the arg into two.
-			arg = parts[0]                         // the first part is the flag name.
-			argv = append(argv[:i+1], argv[i:]...) // insert the second part into the argv.
-			argv[i+1] = parts[1]                   // set the second part as the next arg.
-		}
-
-		var name string
-		if arg[0] == '-' && arg[1] == '-' {
-			// long flag
-			name = arg[2:]
-		} else if arg[0] == '-' {
-			// short flag
-			name = arg[1:]
-		} else {
+		switch tok.kind {
+		case tokenTerminator:
 			continue
-			// flag value. will be consumed by looking at the next arg.
-		}
+		case tokenPositional:
+			if lex.terminated {
+				subcmd.rest = append(subcmd.rest, tok.value)
+				if err := bindRestArg(subcmd.args, len(subcmd.operands), tok.value); err != nil {
+					return nil, err
+				}
+				subcmd.operands = append(subcmd.operands, tok.value)
+				continue
+			}
+
+			if strings.TrimSpace(tok.value) == "" {
+				continue
+			}
+
+			// A nested subcommand can only appear before any positional argument.
+			if len(subcmd.operands) == 0 {
+				if child := findSubCommand(subcmd.subcommands, tok.value); child != nil {
+					subcmd = child
+					subcmd.operands, subcmd.rest = nil, nil
+					flags = subcmd.allFlags()
+					continue
+				}
+			}
 
-		if isHelpFlag(name) {
-			subcmd.PrintUsage(os.Stdout)
-			os.Exit(0)
+			// positional argument of the subcommand.
+			if err := parseArg(subcmd.args, len(subcmd.operands), tok.value); err != nil {
+				return nil, err
+			}
+			subcmd.operands = append(subcmd.operands, tok.value)
+			continue
 		}
 
-		flag, err := parseFlags(&subcmd.flags, name, i, argv)
+		parsed, help, err := parseFlagToken(flags, tok, lex)
 		if err != nil {
 			return nil, err
 		}
 
-		if flag != nil {
-			// Store the processed flag.
-			// This is used to check if all required subcommand flags are present.
-			processedSubCommandFlags[flag.name] = true
+		if help {
+			subcmd.PrintUsage(c.stdout())
+			return nil, ErrHelpRequested
+		}
+
+		// Record the source of the flags.
+		// This is used to check if all required subcommand flags are present.
+		for _, flag := range parsed {
+			flag.source = SourceArgv
 		}
 	}
 
-	// check if all required subcommand flags are present.
-	for _, flag := range subcmd.flags {
-		if _, found := processedSubCommandFlags[flag.name]; !found && flag.required {
-			return nil, fmt.Errorf("missing required flag [-%s | --%s]", flag.shortName, flag.name)
+	// Flags of the subcommand and its ancestors fall back to environment
+	// variables and then to the configuration file.
+	for cmd := subcmd; cmd != nil; cmd = cmd.parent {
+		if err := c.applyEnv(cmd.flags, cmd); err != nil {
+			return nil, err
+		}
+
+		if err := config.apply(cmd.flags, cmd); err != nil {
+			return nil, err
 		}
 	}
 
+	// check if all required flags of the subcommand and its ancestors are present.
+	for cmd := subcmd; cmd != nil; cmd = cmd.parent {
+		for _, flag := range cmd.flags {
+			if !flag.Changed() && flag.required {
+				return nil, fmt.Errorf("missing required flag [-%s | --%s]", flag.shortName, flag.name)
+			}
+		}
+
+		if err := checkConstraints(cmd.constraints, cmd.allFlags()); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := checkRequiredArgs(subcmd.args, len(subcmd.operands)); err != nil {
+		return nil, err
+	}
 	return subcmd, nil
 }
 
+// Parse a flag token of the command line, taking the value of the flag from
+// the lexer if it is not given inline.
+//
+// Returns the flags that were set and whether the help flag was given.
+func parseFlagToken(flags []*Flag, tok token, lex *lexer) ([]*Flag, bool, error) {
+	if tok.kind == tokenInvalid {
+		return nil, false, fmt.Errorf("invalid flag : %s", tok.text)
+	}
+
+	// getopt style cluster of short flags. e.g -xvf or -p8080
+	if isShortCluster(flags, tok) {
+		clustered, err := parseShortCluster(flags, tok, lex)
+		if err != nil {
+			return nil, false, err
+		}
+		return clustered, slices.ContainsFunc(clustered, func(flag *Flag) bool { return isHelpFlag(flag.name) }), nil
+	}
+
+	if isHelpFlag(tok.name) {
+		return nil, true, nil
+	}
+
+	flag, err := parseFlags(flags, tok, lex)
+	if err != nil {
+		return nil, false, err
+	}
+	return []*Flag{flag}, false, nil
+}
+
 // Helper to Parse the flags.
 // flags: The flags to parse.
-// name: The name of the flag, may be the short name.
-// i: The index of the flag in the argv.
-// argv: The arguments.
-func parseFlags(flags *[]*Flag, name string, i int, argv []string) (*Flag, error) {
-	flag := findFlag(*flags, name)
+// tok: The flag token. The name may be the short name.
+// lex: The lexer, the value of the flag is the next arg unless given inline.
+//
+// Returns the matching flag.
+func parseFlags(flags []*Flag, tok token, lex *lexer) (*Flag, error) {
+	name := tok.name
+	flag := findFlag(flags, name)
 	if flag == nil {
-		return nil, fmt.Errorf("unknown flag : %s", name)
+		// --no-<name> sets a negatable bool flag to false.
+		negated := findNegatedFlag(flags, name)
+		if negated == nil {
+			return nil, fmt.Errorf("unknown flag : %s", name)
+		}
+
+		if tok.hasValue {
+			return negated, fmt.Errorf("flag --%s does not take a value", name)
+		}
+		return negated, parseFlagValue(negated, "false")
+	}
+
+	// A count flag is incremented unless a value is given with =. e.g --verbose=3
+	if flag.flagType == flagCount && !tok.hasValue {
+		flag.increment()
+		return flag, nil
 	}
 
-	// look at the next arg for the value.
-	valueIndex := i + 1
-	if (valueIndex) >= len(argv) {
-		if flag.flagType == flagBool { // bool falg may have no value associated. e.g. --verbose
-			*flag.value.(*bool) = true
-			return flag, nil
+	if flag.isBool() && !tok.hasValue {
+		// bool flag may have no value associated. e.g. --verbose
+		// The next arg is only consumed if it is a bool literal so that
+		// a positional argument or subcommand following the flag is preserved.
+		if next, ok := lex.peek(); !ok || !isBoolLiteral(next) {
+			return flag, parseFlagValue(flag, "true")
 		}
-		return flag, fmt.Errorf("missing value for flag [-%s | --%s]", flag.shortName, flag.name)
 	}
 
-	if argv[valueIndex] == "" {
-		// empty string, accessing argv[valueIndex][0] will panic.
+	// A value given with = is taken as is. e.g --offset=-5
+	value := tok.value
+	if !tok.hasValue {
+		next, ok := lex.peek()
+		if !ok || !flag.acceptsValue(next) {
+			return flag, fmt.Errorf("missing value for flag [-%s | --%s]", flag.shortName, flag.name)
+		}
+		value = next
+	}
+
+	if value == "" {
 		return flag, fmt.Errorf("empty value for flag [-%s | --%s]", flag.shortName, flag.name)
 	}
 
-	if argv[valueIndex][0] == '-' {
-		if flag.flagType == flagBool { // bool falg may have no value.
-			*flag.value.(*bool) = true
-			return flag, nil
-		}
-		return flag, fmt.Errorf("missing value for flag [-%s | --%s]", flag.shortName, flag.name)
+	if !tok.hasValue {
+		lex.takeValue()
 	}
 
-	var err error
-	value := argv[valueIndex]
-	err = parseFlagValue(flag, value)
-	if err != nil {
+	if err := parseArgvValue(flag, value); err != nil {
 		return flag, err
 	}
+	return flag, validateFlagValue(flag)
+}
+
+// Reports whether tok is a cluster of single-character short flags.
+// A single-dash arg that exactly matches a flag name (e.g -name) is not a cluster.
+func isShortCluster(flags []*Flag, tok token) bool {
+	return tok.kind == tokenCluster && findFlag(flags, tok.name) == nil
+}
 
-	// validate the flag by calling all validators in sequence.
+// Parse a cluster of short flags like -xvf or -p8080.
+// Each bool flag in the cluster is set to true. The first non-bool flag
+// takes the rest of the cluster as its value, or the next arg if it is the
+// last flag in the cluster. A value given with = is the value of the last
+// flag. e.g -vp=8080 or -vx=false
+//
+// Returns the flags in the cluster.
+func parseShortCluster(flags []*Flag, tok token, lex *lexer) ([]*Flag, error) {
+	var matched []*Flag
+
+	cluster := tok.text[1:]
+	for j, r := range tok.name {
+		short := string(r)
+		flag := findShortFlag(flags, short)
+		if flag == nil {
+			return nil, fmt.Errorf("unknown flag : %s in %s", short, tok.text)
+		}
+		matched = append(matched, flag)
+
+		// The help flag has no value, Parse prints the usage.
+		if isHelpFlag(flag.name) {
+			continue
+		}
+
+		// The rest of the cluster. e.g 8080 in -p8080 or =8080 in -vp=8080
+		rest := cluster[j+len(short):]
+		inline, hasInline := strings.CutPrefix(rest, "=")
+
+		if (flag.isBool() || flag.flagType == flagCount) && !hasInline {
+			if flag.flagType == flagCount {
+				flag.increment()
+			} else if err := parseFlagValue(flag, "true"); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// The rest of the cluster is the value. e.g -p8080
+		value := rest
+		if hasInline {
+			value = inline
+		}
+
+		if value == "" && !hasInline {
+			if next, ok := lex.peek(); ok && next != "" && flag.acceptsValue(next) {
+				value = lex.takeValue().value
+			}
+		}
+
+		if value == "" {
+			return nil, fmt.Errorf("missing value for flag [-%s | --%s] in %s", flag.shortName, flag.name, tok.text)
+		}
+
+		if err := parseArgvValue(flag, value); err != nil {
+			return nil, fmt.Errorf("invalid value for flag [-%s | --%s] in %s: %w", flag.shortName, flag.name, tok.text, err)
+		}
+
+		if err := validateFlagValue(flag); err != nil {
+			return nil, err
+		}
+		return matched, nil
+	}
+	return matched, nil
+}
+
+// Find a flag by its short name only.
+func findShortFlag(flags []*Flag, shortName string) *Flag {
+	for _, flag := range flags {
+		if flag.shortName != "" && flag.shortName == shortName {
+			return flag
+		}
+	}
+	return nil
+}
+
+// Validate the flag by calling all validators in sequence.
+func validateFlagValue(flag *Flag) error {
 	for _, validator := range flag.validators {
 		if validator != nil {
-			// dereference the pointer to get the value.
-			value := reflect.ValueOf(flag.value).Elem().Interface()
+			value := flag.current()
 			if valid, errMsg := validator(value); !valid {
-				return flag, fmt.Errorf("invalid value (%v) for flag [--%s]: %v", value, flag.name, errMsg)
+				if flag.positional {
+					return fmt.Errorf("invalid value (%v) for argument <%s>: %v", value, flag.name, errMsg)
+				}
+				return fmt.Errorf("invalid value (%v) for flag [--%s]: %v", value, flag.name, errMsg)
 			}
 		}
 	}
-	return flag, nil
+	return nil
+}
+
+// Reports whether value can be parsed as a bool.
+func isBoolLiteral(value string) bool {
+	_, err := strconv.ParseBool(value)
+	return err == nil
 }
 
 // Print a flag to the writer.
 // Called by PrintUsage for each flag.
-func printFlag(flag *Flag, w io.Writer, longestFlagName int, indent string) {
-	fmt.Fprintf(w, "%s--%-*s ", indent, longestFlagName, flag.name)
-	valid := reflect.ValueOf(flag.value).IsValid()
+func printFlag(flag *Flag, w io.Writer, longestFlagName int, indent string, env string) {
+	fmt.Fprintf(w, "%s--%-*s ", indent, longestFlagName, flag.displayName())
 	value := ""
-	if valid {
-		value = fmt.Sprintf("%v", reflect.ValueOf(flag.value).Elem().Interface())
+	if _, isMap := mapElemType[flag.flagType]; isMap || flag.flagType == flagCustom {
+		value = flag.Value().String()
+	} else if reflect.ValueOf(flag.value).IsValid() {
+		value = fmt.Sprintf("%v", flag.current())
 	}
 
+	var details string
 	if flag.flagType == flagString {
-		if flag.shortName != "" {
-			fmt.Fprintf(w, "-%s: %s (default: %q)\n", flag.shortName, flag.usage, value)
-		} else {
-			fmt.Fprintf(w, "%s (default: %q)\n", flag.usage, value)
-		}
+		details = fmt.Sprintf("(default: %q)", value)
+	} else if flag.repeatable() {
+		details = fmt.Sprintf("(default: %v, repeatable)", value)
 	} else {
-		if flag.shortName != "" {
-			fmt.Fprintf(w, "-%s: %s (default: %v)\n", flag.shortName, flag.usage, value)
-		} else {
-			fmt.Fprintf(w, "%s (default: %v)\n", flag.usage, value)
-		}
+		details = fmt.Sprintf("(default: %v)", value)
+	}
+
+	// environment variable the flag falls back to.
+	if env != "" {
+		details += fmt.Sprintf(" [env: %s]", env)
 	}
 
+	if flag.shortName != "" {
+		fmt.Fprintf(w, "-%s: %s %s\n", flag.shortName, flag.usageText(), details)
+	} else {
+		fmt.Fprintf(w, "%s %s\n", flag.usageText(), details)
+	}
 }
 
 // Parse the flag value and set the flag value.
 func findFlag(flags []*Flag, name string) *Flag {
 	for index := range flags {
 		flag := flags[index]
-		if flag.name == name || flag.shortName == name {
+		if flag.name == name || (flag.shortName != "" && flag.shortName == name) {
 			return flag
 		}
 	}
@@ -410,19 +892,29 @@ func isHelpFlag(name string) bool {
 	return name == "help" || name == "h"
 }
 
-// Print a subcommand to the writer.
+// Print a subcommand and its nested subcommands to the writer.
 // Called by PrintUsage for each subcommand.
-func printSubCommand(cmd *subcommand, w io.Writer) {
-	fmt.Fprintf(w, "%s: %s", cmd.name, cmd.description)
+// If inherited is true, the flags inherited from parent subcommands are printed too.
+func printSubCommand(cmd *subcommand, w io.Writer, inherited bool) {
+	if len(cmd.args) > 0 {
+		fmt.Fprintf(w, "%s %s: %s", cmd.Path(), argsSynopsis(cmd.args), cmd.description)
+	} else {
+		fmt.Fprintf(w, "%s: %s", cmd.Path(), cmd.description)
+	}
 	fmt.Fprintln(w)
 
+	var inheritedFlags []*Flag
+	if inherited {
+		inheritedFlags = cmd.inheritedFlags()
+	}
+
 	longestFlagName := 0
-	for _, flag := range cmd.flags {
+	for _, flag := range slices.Concat(cmd.flags, inheritedFlags) {
 		if flag.name == "help" {
 			continue
 		}
-		if len(flag.name) > longestFlagName {
-			longestFlagName = len(flag.name)
+		if len(flag.displayName()) > longestFlagName {
+			longestFlagName = len(flag.displayName())
 		}
 	}
 
@@ -431,10 +923,35 @@ func printSubCommand(cmd *subcommand, w io.Writer) {
 		if flag.name == "help" {
 			continue
 		}
-		printFlag(flag, w, longestFlagName, "    ")
+		printFlag(flag, w, longestFlagName, "    ", cmd.cli.envName(flag, cmd))
+	}
+
+	// print the flags inherited from parent subcommands.
+	if len(inheritedFlags) > 0 {
+		fmt.Fprintf(w, "  Inherited Flags:\n")
+		for _, flag := range inheritedFlags {
+			printFlag(flag, w, longestFlagName, "    ", cmd.cli.envName(flag, cmd.flagOwner(flag)))
+		}
+	}
+
+	// print the subcommand arguments.
+	if len(cmd.args) > 0 {
+		fmt.Fprintf(w, "  Arguments:\n")
+		printArgs(cmd.args, w, "    ")
+	}
+
+	// print the constraints between the subcommand flags.
+	if len(cmd.constraints) > 0 {
+		fmt.Fprintf(w, "  Constraints:\n")
+		printConstraints(cmd.constraints, w, "    ")
 	}
 
 	fmt.Fprintln(w)
+
+	// print the nested subcommands.
+	for _, child := range cmd.subcommands {
+		printSubCommand(child, w, false)
+	}
 }
 
 // Print the usage to the writer.
@@ -447,8 +964,8 @@ func printSubCommand(cmd *subcommand, w io.Writer) {
 func (c *CLI) PrintUsage(w io.Writer) {
 	longestFlagName := 0
 	for _, flag := range c.flags {
-		if len(flag.name) > longestFlagName {
-			longestFlagName = len(flag.name)
+		if len(flag.displayName()) > longestFlagName {
+			longestFlagName = len(flag.displayName())
 		}
 	}
 
@@ -460,18 +977,37 @@ func (c *CLI) PrintUsage(w io.Writer) {
 		}
 	}
 
-	fmt.Fprintf(w, "Usage: %s [global flags] [subcommand] [subcommand flags]\n", os.Args[0])
+	if len(c.args) > 0 {
+		fmt.Fprintf(w, "Usage: %s [global flags] %s\n", os.Args[0], argsSynopsis(c.args))
+		fmt.Fprintf(w, "       %s [global flags] [subcommand] [subcommand flags]\n", os.Args[0])
+	} else {
+		fmt.Fprintf(w, "Usage: %s [global flags] [subcommand] [subcommand flags]\n", os.Args[0])
+	}
 	// print the global flags.
 	fmt.Fprintf(w, "Global Flags:\n")
 	for _, flag := range c.flags {
-		printFlag(flag, w, longestFlagName, "  ")
+		printFlag(flag, w, longestFlagName, "  ", c.envName(flag, nil))
 	}
 
 	fmt.Fprintln(w)
 
+	// print the constraints between the global flags.
+	if len(c.constraints) > 0 {
+		fmt.Fprintf(w, "Constraints:\n")
+		printConstraints(c.constraints, w, "  ")
+		fmt.Fprintln(w)
+	}
+
+	// print the positional arguments.
+	if len(c.args) > 0 {
+		fmt.Fprintf(w, "Arguments:\n")
+		printArgs(c.args, w, "  ")
+		fmt.Fprintln(w)
+	}
+
 	// print the subcommands.
 	fmt.Fprintf(w, "Subcommands:\n")
 	for _, cmd := range c.subcommands {
-		printSubCommand(cmd, w)
+		printSubCommand(cmd, w, false)
 	}
 }
@@ -2,7 +2,9 @@ package goflag
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -326,3 +328,392 @@ func TestGlobalRequiredFlags(t *testing.T) {
 	}
 
 }
+
+func TestPositionalArgs(t *testing.T) {
+	cli := New()
+
+	var (
+		verbose bool
+		src     string
+		dst     string
+		count   int
+		files   []string
+		ports   []int
+	)
+
+	cli.Bool("verbose", "v", &verbose, "Enable verbose output")
+	cli.SubCommand("copy", "Copy a file", func() {}).
+		Arg("src", &src, "Source file").Required().
+		Arg("dst", &dst, "Destination file").Required().
+		Int("count", "c", &count, "Number of copies")
+
+	cli.SubCommand("rm", "Remove files", func() {}).
+		Arg("files", &files, "Files to remove").Required()
+
+	argv := []string{"myapp", "--verbose", "copy", "a.txt", "--count", "2", "b.txt"}
+	subcmd, err := cli.Parse(argv)
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if subcmd == nil || subcmd.name != "copy" {
+		t.Fatalf("Expected subcommand 'copy', but got %v", subcmd)
+	}
+
+	if !verbose || src != "a.txt" || dst != "b.txt" || count != 2 {
+		t.Errorf("Unexpected values: verbose=%v src=%q dst=%q count=%d", verbose, src, dst, count)
+	}
+
+	files = []string{"default"}
+	if _, err := cli.Parse([]string{"myapp", "rm", "a", "b", "c"}); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if !reflect.DeepEqual(files, []string{"a", "b", "c"}) {
+		t.Errorf("Expected variadic argument to be [a b c], but got %v", files)
+	}
+
+	if _, err := cli.Parse([]string{"myapp", "copy", "a.txt"}); err == nil {
+		t.Errorf("Expected error for missing required argument")
+	}
+
+	if _, err := cli.Parse([]string{"myapp", "copy", "a.txt", "b.txt", "c.txt"}); err == nil {
+		t.Errorf("Expected error for unexpected argument")
+	}
+
+	if _, err := cli.Parse([]string{"myapp", "rm"}); err == nil {
+		t.Errorf("Expected error for missing variadic argument")
+	}
+
+	// Global positional arguments with validators.
+	cli = New()
+	cli.Arg("ports", &ports, "Ports to scan").Validate(func(value any) (bool, string) {
+		for _, port := range value.([]int) {
+			if port <= 0 {
+				return false, "ports must be positive"
+			}
+		}
+		return true, ""
+	})
+
+	if _, err := cli.Parse([]string{"myapp", "80", "443"}); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if !reflect.DeepEqual(ports, []int{80, 443}) {
+		t.Errorf("Expected ports to be [80 443], but got %v", ports)
+	}
+
+	if _, err := cli.Parse([]string{"myapp", "80", "0"}); err == nil {
+		t.Errorf("Expected validation error for port 0")
+	}
+
+	if _, err := cli.Parse([]string{"myapp", "http"}); err == nil {
+		t.Errorf("Expected error for invalid int argument")
+	}
+
+	var buf bytes.Buffer
+	cli.PrintUsage(&buf)
+	if !strings.Contains(buf.String(), "[ports...]") || !strings.Contains(buf.String(), "Ports to scan") {
+		t.Errorf("Expected usage to contain positional arguments, got:\n%s", buf.String())
+	}
+}
+
+func TestArgPanics(t *testing.T) {
+	cli := New()
+	var files []string
+	var name string
+
+	cli.Arg("files", &files, "Files")
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Expected argument after variadic argument to panic")
+		}
+	}()
+	cli.Arg("name", &name, "Name")
+}
+
+func TestTerminator(t *testing.T) {
+	cli := New()
+
+	var (
+		verbose bool
+		command []string
+	)
+
+	cli.Bool("verbose", "v", &verbose, "Enable verbose output")
+	exec := cli.SubCommand("exec", "Run a command", func() {}).
+		Arg("command", &command, "Command to run")
+
+	argv := []string{"myapp", "exec", "--verbose", "--", "rm", "-rf", "--dry-run", "--"}
+	_, err := cli.Parse(argv)
+	if err == nil {
+		t.Fatalf("Expected unknown flag error for --verbose after the subcommand")
+	}
+
+	argv = []string{"myapp", "-v", "exec", "--", "rm", "-rf", "--dry-run", "--"}
+	subcmd, err := cli.Parse(argv)
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if subcmd != exec {
+		t.Fatalf("Expected exec subcommand, but got %v", subcmd)
+	}
+
+	want := []string{"rm", "-rf", "--dry-run", "--"}
+	if !reflect.DeepEqual(subcmd.Rest(), want) {
+		t.Errorf("Expected rest to be %v, but got %v", want, subcmd.Rest())
+	}
+
+	if !reflect.DeepEqual(command, want) {
+		t.Errorf("Expected command to be %v, but got %v", want, command)
+	}
+
+	// Subcommand names and flags after -- are positional for the CLI.
+	cli = New()
+	cli.Bool("verbose", "v", &verbose, "Enable verbose output")
+	verbose = false
+	subcmd, err = cli.Parse([]string{"myapp", "--", "completion", "-v"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if subcmd != nil || verbose {
+		t.Errorf("Expected no subcommand and no flags after --, got %v, verbose=%v", subcmd, verbose)
+	}
+
+	if !reflect.DeepEqual(cli.Rest(), []string{"completion", "-v"}) {
+		t.Errorf("Expected rest to be [completion -v], but got %v", cli.Rest())
+	}
+
+	if !reflect.DeepEqual(cli.Args(), []string{"completion", "-v"}) {
+		t.Errorf("Expected args to be [completion -v], but got %v", cli.Args())
+	}
+}
+
+func TestShortFlagClusters(t *testing.T) {
+	var (
+		extract bool
+		verbose bool
+		file    string
+		port    int
+		name    string
+	)
+
+	newCLI := func() *CLI {
+		extract, verbose, file, port, name = false, false, "", 0, ""
+		cli := New()
+		cli.Bool("extract", "x", &extract, "Extract files")
+		cli.Bool("verbose", "v", &verbose, "Verbose output")
+		cli.String("file", "f", &file, "Archive file")
+		cli.Int("port", "p", &port, "Port to listen on")
+		cli.String("name", "", &name, "Single-dash long name")
+		return cli
+	}
+
+	cli := newCLI()
+	if _, err := cli.Parse([]string{"myapp", "-xvf", "archive.tar"}); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+	if !extract || !verbose || file != "archive.tar" {
+		t.Errorf("Expected -xvf to set x, v and f, got x=%v v=%v f=%q", extract, verbose, file)
+	}
+
+	cli = newCLI()
+	if _, err := cli.Parse([]string{"myapp", "-p8080", "-vxfout.tar"}); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+	if port != 8080 || !verbose || !extract || file != "out.tar" {
+		t.Errorf("Expected attached values, got port=%d v=%v x=%v f=%q", port, verbose, extract, file)
+	}
+
+	// Single-dash long names still take precedence over clusters.
+	cli = newCLI()
+	if _, err := cli.Parse([]string{"myapp", "-name", "John"}); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+	if name != "John" {
+		t.Errorf("Expected name to be John, got %q", name)
+	}
+
+	errorCases := [][]string{
+		{"myapp", "-vf"},               // missing value for last flag
+		{"myapp", "-vf", "-x"},         // value looks like a flag
+		{"myapp", "-vq"},               // unknown flag in cluster
+		{"myapp", "-pabc"},             // invalid attached value
+		{"myapp", "completion", "-si"}, // s requires a value
+	}
+
+	for _, argv := range errorCases {
+		cli = newCLI()
+		if _, err := cli.Parse(argv); err == nil {
+			t.Errorf("Expected error for %v", argv)
+		}
+	}
+
+	// Subcommand flags can be clustered too.
+	var shell string
+	var install bool
+	cli = New()
+	cli.SubCommand("setup", "Setup", func() {}).
+		String("shell", "s", &shell, "Shell").
+		Bool("install", "i", &install, "Install")
+
+	if _, err := cli.Parse([]string{"myapp", "setup", "-isbash"}); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+	if !install || shell != "bash" {
+		t.Errorf("Expected install and shell=bash, got install=%v shell=%q", install, shell)
+	}
+}
+
+func TestNestedSubCommands(t *testing.T) {
+	cli := New()
+
+	var (
+		env     string
+		steps   int
+		dryRun  bool
+		version string
+	)
+
+	db := cli.SubCommand("db", "Database commands", func() {}).
+		String("env", "e", &env, "Environment").Required()
+
+	migrate := db.SubCommand("migrate", "Run migrations", func() {}).
+		Bool("dry-run", "n", &dryRun, "Print the migrations without running them")
+
+	up := migrate.SubCommand("up", "Migrate up", func() {}).
+		Int("steps", "s", &steps, "Number of steps").
+		Arg("version", &version, "Target version")
+
+	subcmd, err := cli.Parse([]string{"myapp", "db", "--env", "prod", "migrate", "-n", "up", "-s", "2", "--env", "dev", "v42"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if subcmd != up {
+		t.Fatalf("Expected the deepest subcommand 'up', but got %v", subcmd)
+	}
+
+	if subcmd.Path() != "db migrate up" {
+		t.Errorf("Expected path 'db migrate up', but got %q", subcmd.Path())
+	}
+
+	if subcmd.Parent() != migrate || migrate.Parent() != db || db.Parent() != nil {
+		t.Errorf("Unexpected parent chain")
+	}
+
+	// Inherited flags can be given at any level below the subcommand that defines them.
+	if env != "dev" || steps != 2 || !dryRun || version != "v42" {
+		t.Errorf("Unexpected values: env=%q steps=%d dryRun=%v version=%q", env, steps, dryRun, version)
+	}
+
+	// Required flags of ancestors are enforced.
+	if _, err := cli.Parse([]string{"myapp", "db", "migrate", "up"}); err == nil {
+		t.Errorf("Expected error for missing inherited required flag")
+	}
+
+	// Flags of children are not accepted by parents.
+	if _, err := cli.Parse([]string{"myapp", "db", "-e", "prod", "--steps", "1", "migrate"}); err == nil {
+		t.Errorf("Expected error for child flag used on the parent")
+	}
+
+	subcmd, err = cli.Parse([]string{"myapp", "db", "-e", "prod", "migrate"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if subcmd != migrate {
+		t.Errorf("Expected subcommand 'migrate', but got %v", subcmd)
+	}
+
+	var buf bytes.Buffer
+	up.PrintUsage(&buf)
+	for _, expected := range []string{"db migrate up", "--steps", "Inherited Flags:", "--env", "--dry-run"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected subcommand usage to contain %q, got:\n%s", expected, buf.String())
+		}
+	}
+
+	buf.Reset()
+	cli.GenBashCompletion(&buf)
+	if !strings.Contains(buf.String(), "/db/migrate/up") {
+		t.Errorf("Expected bash completion to contain the nested subcommand path")
+	}
+
+	buf.Reset()
+	cli.GenZshCompletion(&buf)
+	if !strings.Contains(buf.String(), "_db_migrate_up()") {
+		t.Errorf("Expected zsh completion to contain a function for the nested subcommand")
+	}
+}
+
+func TestCompletionBypassPerCLI(t *testing.T) {
+	var config string
+	first := New().SetOutput(&bytes.Buffer{})
+	first.String("config", "c", &config, "Config file").Required()
+
+	// Creating another CLI must not affect the completion command of the first.
+	second := New()
+	second.String("config", "c", &config, "Config file").Required()
+
+	if _, err := first.Parse([]string{"myapp", "completion", "--shell", "bash"}); err != nil {
+		t.Errorf("Expected completion to bypass required global flags, but got '%v'", err)
+	}
+
+	if _, err := second.Parse([]string{"myapp", "completion", "--shell", "zsh"}); err != nil {
+		t.Errorf("Expected completion to bypass required global flags, but got '%v'", err)
+	}
+
+	if _, err := first.Parse([]string{"myapp"}); err == nil {
+		t.Errorf("Expected error for missing required global flag")
+	}
+}
+
+// Independent CLIs are safe to build and parse in parallel.
+// Run with -race to detect shared state.
+func TestConcurrentCLIs(t *testing.T) {
+	for i := range 16 {
+		t.Run(fmt.Sprintf("cli-%d", i), func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				out    bytes.Buffer
+				config string
+				port   int
+				files  []string
+			)
+
+			cli := New().SetOutput(&out)
+			cli.String("config", "c", &config, "Config file").Required()
+			cli.SubCommand("serve", "Start the server", func() {}).
+				Int("port", "p", &port, "Port").
+				Arg("files", &files, "Files to serve")
+
+			if err := cli.Run(context.Background(), []string{"myapp", "completion", "-s", "bash"}); err != nil {
+				t.Fatalf("Expected no error, but got '%v'", err)
+			}
+
+			if !strings.Contains(out.String(), "complete -F") {
+				t.Errorf("Expected completion script in output")
+			}
+
+			want := fmt.Sprintf("config-%d.json", i)
+			subcmd, err := cli.Parse([]string{"myapp", "-c", want, "serve", fmt.Sprintf("-p%d", 8000+i), "a", "b"})
+			if err != nil {
+				t.Fatalf("Expected no error, but got '%v'", err)
+			}
+
+			if subcmd == nil || config != want || port != 8000+i || len(files) != 2 {
+				t.Errorf("Unexpected values: config=%q port=%d files=%v", config, port, files)
+			}
+
+			if _, err := cli.Parse([]string{"myapp", "serve"}); err == nil {
+				t.Errorf("Expected error for missing required global flag")
+			}
+		})
+	}
+}
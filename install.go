@@ -0,0 +1,534 @@
+package goflag
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// InstallOptions configures where and how completion scripts are installed
+// and uninstalled. The zero value installs for the current user.
+type InstallOptions struct {
+	// Home is the home directory of the user. Defaults to os.UserHomeDir.
+	Home string
+
+	// Root is prepended to every path that is written or removed, like
+	// DESTDIR. The paths in the rc files are not prefixed.
+	Root string
+
+	// System installs to the system-wide completion directories instead of
+	// the home directory. No rc file is changed.
+	System bool
+
+	// DryRun prints the plan without changing any file.
+	DryRun bool
+
+	// Dynamic installs the script that completes values at runtime.
+	// See GenBashDynamicCompletion.
+	Dynamic bool
+
+	// Getenv looks up XDG_DATA_HOME, XDG_CONFIG_HOME and ZDOTDIR.
+	// Defaults to os.Getenv.
+	Getenv func(key string) string
+
+	// Stdout and Stderr receive the progress and the warnings.
+	// Default to the output and error writers of the CLI.
+	Stdout, Stderr io.Writer
+}
+
+// The files changed by installing the completion script of a shell.
+// Paths are final, without InstallOptions.Root.
+type installPlan struct {
+	shell  string // the display name of the shell. e.g Bash
+	script string // path of the completion script.
+	rcFile string // rc file that loads the script, empty if the shell loads it on its own.
+	block  string // the lines of the marked block added to rcFile.
+	hint   string // how to enable the completions after installing.
+	legacy legacyInstall
+}
+
+// The files of a completion script installed before InstallOptions, in
+// ~/.bash_completion.d or ~/.zsh/completion with unmarked lines in the rc file.
+// They are removed on install and uninstall so that the completions are not
+// registered twice.
+type legacyInstall struct {
+	script string // path of the completion script, empty if there is no legacy layout.
+	rcFile string // rc file with the line that loads the script.
+	line   string // the line added to rcFile.
+	shared string // directory shared with other scripts, the line is kept until it is empty.
+}
+
+// InstallCompletion installs the completion script of the given shell (bash,
+// zsh or fish) for the current user. See InstallCompletionWithOptions.
+//
+// Example:
+//
+//	cli := goflag.New()
+//	if err := cli.InstallCompletion("bash"); err != nil {
+//	    fmt.Fprintf(os.Stderr, "Failed to install completion: %v\n", err)
+//	}
+func (c *CLI) InstallCompletion(shell string) error {
+	return c.InstallCompletionWithOptions(shell, InstallOptions{})
+}
+
+// InstallCompletionWithOptions installs the completion script of the given
+// shell (bash, zsh or fish).
+//
+// For the current user, the script is installed to:
+//   - bash: $XDG_DATA_HOME/bash-completion/completions/<binName>, sourced from ~/.bashrc
+//   - zsh: $XDG_DATA_HOME/zsh/site-functions/_<binName>, added to fpath in $ZDOTDIR/.zshrc
+//   - fish: $XDG_CONFIG_HOME/fish/completions/<binName>.fish, loaded by fish
+//
+// XDG_DATA_HOME defaults to ~/.local/share and XDG_CONFIG_HOME to ~/.config.
+// The lines added to an rc file are in a marked block that is replaced on
+// reinstall and removed by UninstallCompletionWithOptions. The rc file is
+// backed up to <rc>.bak before it is changed. If it is a symlink, the file
+// it points to is changed. Scripts installed by earlier versions are removed,
+// see UninstallCompletionWithOptions.
+//
+// System-wide, the script is installed to /usr/share/bash-completion/completions,
+// /usr/local/share/zsh/site-functions or /usr/share/fish/vendor_completions.d.
+//
+// Files are written atomically.
+func (c *CLI) InstallCompletionWithOptions(shell string, opts InstallOptions) error {
+	opts = c.installDefaults(opts)
+
+	generate, err := c.completionGenerator(shell, opts.Dynamic)
+	if err != nil {
+		return err
+	}
+
+	plan, err := c.installPlan(shell, opts)
+	if err != nil {
+		return err
+	}
+
+	var script strings.Builder
+	generate(&script)
+
+	scriptPath := opts.path(plan.script)
+	if opts.DryRun {
+		fmt.Fprintf(opts.Stdout, "Would write the %s completion script to: %s\n", plan.shell, scriptPath)
+	} else {
+		if err := os.MkdirAll(filepath.Dir(scriptPath), 0o755); err != nil {
+			return fmt.Errorf("failed to create completion directory: %w", err)
+		}
+
+		if err := writeFileAtomic(scriptPath, []byte(script.String()), 0o644); err != nil {
+			return fmt.Errorf("failed to write completion script: %w", err)
+		}
+		fmt.Fprintf(opts.Stdout, "%s completion installed to: %s\n", plan.shell, scriptPath)
+	}
+
+	if plan.rcFile != "" {
+		rcPath := opts.path(plan.rcFile)
+		if err := updateBlock(rcPath, c.blockName(), plan.block, opts); err != nil {
+			fmt.Fprintf(opts.Stderr, "Warning: Could not update %s: %v\n", rcPath, err)
+			fmt.Fprintf(opts.Stderr, "Add these lines to %s manually:\n%s\n", rcPath, plan.block)
+		}
+	}
+	removeLegacy(plan.legacy, opts)
+
+	if !opts.DryRun {
+		fmt.Fprintln(opts.Stdout, plan.hint)
+	}
+	return nil
+}
+
+// UninstallCompletion removes the completion script of the given shell for
+// the current user. See UninstallCompletionWithOptions.
+//
+// Example:
+//
+//	cli := goflag.New()
+//	if err := cli.UninstallCompletion("bash"); err != nil {
+//	    fmt.Fprintf(os.Stderr, "Failed to uninstall completion: %v\n", err)
+//	}
+func (c *CLI) UninstallCompletion(shell string) error {
+	return c.UninstallCompletionWithOptions(shell, InstallOptions{})
+}
+
+// UninstallCompletionWithOptions removes the completion script installed by
+// InstallCompletionWithOptions with the same options, and the marked block
+// from the rc file, including the fpath lines of zsh.
+//
+// Scripts installed by earlier versions to ~/.bash_completion.d or
+// ~/.zsh/completion are removed too, with the lines that load them. The
+// compinit line of zsh is kept since other completions may need it.
+func (c *CLI) UninstallCompletionWithOptions(shell string, opts InstallOptions) error {
+	opts = c.installDefaults(opts)
+
+	plan, err := c.installPlan(shell, opts)
+	if err != nil {
+		return err
+	}
+
+	scriptPath := opts.path(plan.script)
+	if opts.DryRun {
+		fmt.Fprintf(opts.Stdout, "Would remove the %s completion script: %s\n", plan.shell, scriptPath)
+	} else {
+		if err := os.Remove(scriptPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove completion script: %w", err)
+		}
+		fmt.Fprintf(opts.Stdout, "%s completion uninstalled from: %s\n", plan.shell, scriptPath)
+	}
+
+	if plan.rcFile != "" {
+		rcPath := opts.path(plan.rcFile)
+		if err := updateBlock(rcPath, c.blockName(), "", opts); err != nil {
+			fmt.Fprintf(opts.Stderr, "Warning: Could not update %s: %v\n", rcPath, err)
+			fmt.Fprintf(opts.Stderr, "Remove the %q block manually\n", c.blockName())
+		}
+	}
+	removeLegacy(plan.legacy, opts)
+	return nil
+}
+
+// Fill in the defaults of the options.
+func (c *CLI) installDefaults(opts InstallOptions) InstallOptions {
+	if opts.Getenv == nil {
+		opts.Getenv = os.Getenv
+	}
+
+	if opts.Stdout == nil {
+		opts.Stdout = c.stdout()
+	}
+
+	if opts.Stderr == nil {
+		opts.Stderr = c.stderr()
+	}
+	return opts
+}
+
+// Returns the path of a file on disk, under the root of the options.
+func (opts InstallOptions) path(name string) string {
+	if opts.Root == "" {
+		return name
+	}
+	return filepath.Join(opts.Root, name)
+}
+
+// Returns the value of an XDG base directory variable, or the default
+// under the home directory. Relative values are ignored as required by the spec.
+func (opts InstallOptions) xdgDir(key, home string, elem ...string) string {
+	if dir := opts.Getenv(key); filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(append([]string{home}, elem...)...)
+}
+
+// Returns the files changed by installing the completion script of a shell.
+func (c *CLI) installPlan(shell string, opts InstallOptions) (installPlan, error) {
+	binName := c.binName()
+
+	if opts.System {
+		switch shell {
+		case "bash":
+			return installPlan{
+				shell:  "Bash",
+				script: filepath.Join("/usr", "share", "bash-completion", "completions", binName),
+				hint:   "Restart your terminal to enable completions",
+			}, nil
+		case "zsh":
+			return installPlan{
+				shell:  "Zsh",
+				script: filepath.Join("/usr", "local", "share", "zsh", "site-functions", "_"+binName),
+				hint:   "Restart your terminal to enable completions",
+			}, nil
+		case "fish":
+			return installPlan{
+				shell:  "Fish",
+				script: filepath.Join("/usr", "share", "fish", "vendor_completions.d", binName+".fish"),
+				hint:   "Restart your terminal to enable completions",
+			}, nil
+		}
+		return installPlan{}, fmt.Errorf("unsupported shell: %s (supported: bash, zsh, fish)", shell)
+	}
+
+	home := opts.Home
+	if home == "" {
+		var err error
+		if home, err = os.UserHomeDir(); err != nil {
+			return installPlan{}, fmt.Errorf("failed to get home directory: %w", err)
+		}
+	}
+
+	switch shell {
+	case "bash":
+		script := filepath.Join(opts.xdgDir("XDG_DATA_HOME", home, ".local", "share"), "bash-completion", "completions", binName)
+		rcFile := filepath.Join(home, ".bashrc")
+		legacy := filepath.Join(home, ".bash_completion.d", binName)
+		return installPlan{
+			shell:  "Bash",
+			script: script,
+			rcFile: rcFile,
+			block:  fmt.Sprintf("[ -f %s ] && source %s", shQuote(script), shQuote(script)),
+			hint:   fmt.Sprintf("Run 'source %s' or restart your terminal to enable completions", rcFile),
+			legacy: opts.rooted(legacyInstall{script: legacy, rcFile: rcFile, line: "source " + legacy}),
+		}, nil
+	case "zsh":
+		dir := filepath.Join(opts.xdgDir("XDG_DATA_HOME", home, ".local", "share"), "zsh", "site-functions")
+		zdotdir := opts.Getenv("ZDOTDIR")
+		if !filepath.IsAbs(zdotdir) {
+			zdotdir = home
+		}
+		rcFile := filepath.Join(zdotdir, ".zshrc")
+		legacy := filepath.Join(home, ".zsh", "completion")
+		return installPlan{
+			shell:  "Zsh",
+			script: filepath.Join(dir, "_"+binName),
+			rcFile: rcFile,
+			block:  fmt.Sprintf("fpath=(%s $fpath)\nautoload -Uz compinit && compinit", shQuote(dir)),
+			hint:   fmt.Sprintf("Run 'source %s' or restart your terminal to enable completions", rcFile),
+			legacy: opts.rooted(legacyInstall{
+				script: filepath.Join(legacy, "_"+binName),
+				rcFile: filepath.Join(home, ".zshrc"),
+				line:   "fpath=(~/.zsh/completion $fpath)",
+				shared: legacy,
+			}),
+		}, nil
+	case "fish":
+		plan := installPlan{
+			shell:  "Fish",
+			script: filepath.Join(opts.xdgDir("XDG_CONFIG_HOME", home, ".config"), "fish", "completions", binName+".fish"),
+			hint:   "Restart your terminal to enable completions",
+		}
+
+		// Earlier versions ignored XDG_CONFIG_HOME.
+		if legacy := filepath.Join(home, ".config", "fish", "completions", binName+".fish"); legacy != plan.script {
+			plan.legacy = opts.rooted(legacyInstall{script: legacy})
+		}
+		return plan, nil
+	}
+	return installPlan{}, fmt.Errorf("unsupported shell: %s (supported: bash, zsh, fish)", shell)
+}
+
+// Returns the legacy install with the paths on disk, under the root of the
+// options. The line in the rc file is kept as written.
+func (opts InstallOptions) rooted(legacy legacyInstall) legacyInstall {
+	legacy.script = opts.path(legacy.script)
+	if legacy.rcFile != "" {
+		legacy.rcFile = opts.path(legacy.rcFile)
+	}
+
+	if legacy.shared != "" {
+		legacy.shared = opts.path(legacy.shared)
+	}
+	return legacy
+}
+
+// Remove the script of a legacy install and the line that loads it from the
+// rc file. Failures are reported as warnings.
+func removeLegacy(legacy legacyInstall, opts InstallOptions) {
+	if legacy.script == "" {
+		return
+	}
+
+	if _, err := os.Lstat(legacy.script); err == nil {
+		if opts.DryRun {
+			fmt.Fprintf(opts.Stdout, "Would remove the legacy completion script: %s\n", legacy.script)
+		} else if err := os.Remove(legacy.script); err != nil {
+			fmt.Fprintf(opts.Stderr, "Warning: Could not remove the legacy completion script: %v\n", err)
+		} else {
+			fmt.Fprintf(opts.Stdout, "Removed the legacy completion script: %s\n", legacy.script)
+		}
+	}
+
+	if legacy.rcFile == "" || (legacy.shared != "" && !dirEmptyExcept(legacy.shared, legacy.script)) {
+		return
+	}
+
+	removeLine := func(content string) string {
+		lines := strings.SplitAfter(content, "\n")
+		kept := lines[:0]
+		for _, line := range lines {
+			if strings.TrimSpace(line) != legacy.line {
+				kept = append(kept, line)
+			}
+		}
+		return strings.Join(kept, "")
+	}
+
+	rcPath, changed, err := editFile(legacy.rcFile, removeLine, opts)
+	switch {
+	case err != nil:
+		fmt.Fprintf(opts.Stderr, "Warning: Could not update %s: %v\n", legacy.rcFile, err)
+		fmt.Fprintf(opts.Stderr, "Remove this line manually:\n  %s\n", legacy.line)
+	case changed && opts.DryRun:
+		fmt.Fprintf(opts.Stdout, "Would remove the legacy line from %s:\n  %s\n", rcPath, legacy.line)
+	case changed:
+		fmt.Fprintf(opts.Stdout, "Removed the legacy line from %s:\n  %s\n", rcPath, legacy.line)
+	}
+}
+
+// Returns true if dir is missing or has no entries other than the file at path.
+func dirEmptyExcept(dir, path string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return errors.Is(err, fs.ErrNotExist)
+	}
+
+	for _, entry := range entries {
+		if filepath.Join(dir, entry.Name()) != path {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the name of the marked block of the CLI in rc files.
+func (c *CLI) blockName() string {
+	return "goflag completion for " + c.binName()
+}
+
+// Replace the marked block with the given name in an rc file, append it if
+// it is missing, or remove it if block is empty. See editFile.
+func updateBlock(rcPath, name, block string, opts InstallOptions) error {
+	rcPath, changed, err := editFile(rcPath, func(content string) string {
+		return setBlock(content, name, block)
+	}, opts)
+	if err != nil || !changed {
+		return err
+	}
+
+	switch {
+	case opts.DryRun && block == "":
+		fmt.Fprintf(opts.Stdout, "Would remove the completion block from: %s\n", rcPath)
+	case opts.DryRun:
+		fmt.Fprintf(opts.Stdout, "Would add the completion block to: %s\n%s\n", rcPath, markBlock(name, block))
+	case block == "":
+		fmt.Fprintf(opts.Stdout, "Removed the completion block from: %s\n", rcPath)
+	default:
+		fmt.Fprintf(opts.Stdout, "Added the completion block to: %s\n", rcPath)
+	}
+	return nil
+}
+
+// Change the content of an rc file with edit. Symlinks are followed, so that
+// the file they point to is changed instead of being replaced. The file is
+// backed up to <file>.bak first. Nothing is written if the content does not
+// change or in a dry run. Returns the path of the file that is changed and
+// whether the content changes.
+func editFile(path string, edit func(content string) string, opts InstallOptions) (string, bool, error) {
+	path, err := resolveFile(path)
+	if err != nil {
+		return path, false, err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return path, false, err
+	}
+	exists := err == nil
+
+	updated := edit(string(content))
+	if updated == string(content) || opts.DryRun {
+		return path, updated != string(content), nil
+	}
+
+	perm := fs.FileMode(0o644)
+	if !exists {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return path, false, err
+		}
+	} else {
+		info, err := os.Stat(path)
+		if err != nil {
+			return path, false, err
+		}
+		perm = info.Mode().Perm()
+
+		if err := writeFileAtomic(path+".bak", content, perm); err != nil {
+			return path, false, fmt.Errorf("failed to back up: %w", err)
+		}
+	}
+
+	if err := writeFileAtomic(path, []byte(updated), perm); err != nil {
+		return path, false, err
+	}
+	return path, true, nil
+}
+
+// Returns the path of the file that path refers to after following symlinks.
+// A missing file is returned as is, a symlink to a missing file is an error.
+func resolveFile(path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		if _, err := os.Lstat(path); err == nil {
+			return path, fmt.Errorf("%s is a broken symlink", path)
+		}
+		return path, nil
+	}
+	return resolved, err
+}
+
+// Returns the block between its begin and end markers.
+func markBlock(name, block string) string {
+	return fmt.Sprintf("# >>> %s >>>\n%s\n# <<< %s <<<\n", name, block, name)
+}
+
+// Returns content with the marked block replaced, appended if it is missing,
+// or removed if block is empty.
+func setBlock(content, name, block string) string {
+	begin := "# >>> " + name + " >>>\n"
+	end := "# <<< " + name + " <<<\n"
+
+	start := strings.Index(content, begin)
+	if start >= 0 {
+		if stop := strings.Index(content[start:], end); stop >= 0 {
+			rest := content[start+stop+len(end):]
+			if block == "" {
+				return content[:start] + rest
+			}
+			return content[:start] + markBlock(name, block) + rest
+		}
+	}
+
+	if block == "" {
+		return content
+	}
+
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return content + markBlock(name, block)
+}
+
+// Write a file atomically by writing a temporary file in the same
+// directory and renaming it over the file.
+func writeFileAtomic(path string, data []byte, perm fs.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Quote a string for POSIX shells in single quotes.
+func shQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,338 @@
+package goflag
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Returns install options for a temporary home directory with the given environment.
+func testInstallOptions(t *testing.T, env map[string]string) (InstallOptions, *bytes.Buffer) {
+	var out bytes.Buffer
+	return InstallOptions{
+		Home:   t.TempDir(),
+		Getenv: func(key string) string { return env[key] },
+		Stdout: &out,
+		Stderr: &out,
+	}, &out
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected %s to exist: %v", path, err)
+	}
+	return string(data)
+}
+
+func TestInstallBashCompletion(t *testing.T) {
+	cli := New().SetName("app")
+	opts, _ := testInstallOptions(t, nil)
+
+	bashrc := filepath.Join(opts.Home, ".bashrc")
+	if err := os.WriteFile(bashrc, []byte("export EDITOR=vi"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reinstalling replaces the block.
+	for range 2 {
+		if err := cli.InstallCompletionWithOptions("bash", opts); err != nil {
+			t.Fatalf("Expected no error, but got '%v'", err)
+		}
+	}
+
+	script := filepath.Join(opts.Home, ".local", "share", "bash-completion", "completions", "app")
+	if !strings.HasPrefix(readFile(t, script), "#!/bin/bash\n# Bash completion for app") {
+		t.Errorf("Unexpected completion script")
+	}
+
+	want := "export EDITOR=vi\n" +
+		"# >>> goflag completion for app >>>\n" +
+		"[ -f '" + script + "' ] && source '" + script + "'\n" +
+		"# <<< goflag completion for app <<<\n"
+	if got := readFile(t, bashrc); got != want {
+		t.Errorf("Expected .bashrc:\n%s\ngot:\n%s", want, got)
+	}
+
+	if got := readFile(t, bashrc+".bak"); got != "export EDITOR=vi" {
+		t.Errorf("Expected the original .bashrc as backup, got:\n%s", got)
+	}
+
+	if info, err := os.Stat(bashrc); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected the mode of .bashrc to be kept, got %v (%v)", info.Mode(), err)
+	}
+
+	if err := cli.UninstallCompletionWithOptions("bash", opts); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if _, err := os.Stat(script); !os.IsNotExist(err) {
+		t.Errorf("Expected the completion script to be removed, got %v", err)
+	}
+
+	if got := readFile(t, bashrc); got != "export EDITOR=vi\n" {
+		t.Errorf("Expected the block to be removed, got:\n%s", got)
+	}
+}
+
+func TestInstallZshCompletion(t *testing.T) {
+	cli := New().SetName("app")
+	data, zdotdir := t.TempDir(), t.TempDir()
+	opts, out := testInstallOptions(t, map[string]string{"XDG_DATA_HOME": data, "ZDOTDIR": zdotdir})
+
+	zshrc := filepath.Join(zdotdir, ".zshrc")
+	if err := os.WriteFile(zshrc, []byte("setopt autocd\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cli.InstallCompletionWithOptions("zsh", opts); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	dir := filepath.Join(data, "zsh", "site-functions")
+	if !strings.HasPrefix(readFile(t, filepath.Join(dir, "_app")), "#compdef app") {
+		t.Errorf("Unexpected completion script")
+	}
+
+	if got := readFile(t, zshrc); !strings.Contains(got, "fpath=('"+dir+"' $fpath)\nautoload -Uz compinit && compinit\n") {
+		t.Errorf("Expected the fpath lines in .zshrc, got:\n%s", got)
+	}
+
+	if want := "Run 'source " + zshrc + "'"; !strings.Contains(out.String(), want) {
+		t.Errorf("Expected the hint to name %s, got:\n%s", zshrc, out.String())
+	}
+
+	if err := cli.UninstallCompletionWithOptions("zsh", opts); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if got := readFile(t, zshrc); got != "setopt autocd\n" {
+		t.Errorf("Expected the fpath lines to be removed, got:\n%s", got)
+	}
+}
+
+func TestInstallSymlinkedRcFile(t *testing.T) {
+	cli := New().SetName("app")
+	opts, _ := testInstallOptions(t, nil)
+
+	// A .bashrc managed in a dotfiles repository.
+	target := filepath.Join(t.TempDir(), "dotfiles", "bashrc")
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(target, []byte("export EDITOR=vi\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	bashrc := filepath.Join(opts.Home, ".bashrc")
+	if err := os.Symlink(target, bashrc); err != nil {
+		t.Skipf("Symlinks are not supported: %v", err)
+	}
+
+	if err := cli.InstallCompletionWithOptions("bash", opts); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if info, err := os.Lstat(bashrc); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("Expected .bashrc to stay a symlink, got %v (%v)", info.Mode(), err)
+	}
+
+	if got := readFile(t, target); !strings.Contains(got, "# >>> goflag completion for app >>>") {
+		t.Errorf("Expected the block in the target of the symlink, got:\n%s", got)
+	}
+
+	if got := readFile(t, target+".bak"); got != "export EDITOR=vi\n" {
+		t.Errorf("Expected the backup next to the target, got:\n%s", got)
+	}
+
+	// A broken symlink is not replaced.
+	if err := os.Remove(target); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cli.InstallCompletionWithOptions("bash", opts); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if info, err := os.Lstat(bashrc); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Expected the broken symlink to be kept, got %v (%v)", info.Mode(), err)
+	}
+}
+
+func TestInstallRemovesLegacyCompletion(t *testing.T) {
+	cli := New().SetName("app")
+	opts, out := testInstallOptions(t, nil)
+
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The layout of the previous release.
+	bashScript := filepath.Join(opts.Home, ".bash_completion.d", "app")
+	bashrc := filepath.Join(opts.Home, ".bashrc")
+	write(bashScript, "complete")
+	write(bashrc, "source "+bashScript+"\nexport EDITOR=vi\n")
+
+	zshScript := filepath.Join(opts.Home, ".zsh", "completion", "_app")
+	zshrc := filepath.Join(opts.Home, ".zshrc")
+	write(zshScript, "#compdef app")
+	write(zshrc, "fpath=(~/.zsh/completion $fpath)\nautoload -Uz compinit && compinit\n")
+
+	if err := cli.InstallCompletionWithOptions("bash", opts); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if _, err := os.Stat(bashScript); !os.IsNotExist(err) {
+		t.Errorf("Expected the legacy bash script to be removed, got %v", err)
+	}
+
+	if got := readFile(t, bashrc); strings.Contains(got, "source "+bashScript) || !strings.HasPrefix(got, "export EDITOR=vi\n") {
+		t.Errorf("Expected the legacy source line to be removed, got:\n%s", got)
+	}
+
+	// The fpath line is kept while other scripts use the directory.
+	other := filepath.Join(opts.Home, ".zsh", "completion", "_other")
+	write(other, "#compdef other")
+	if err := cli.UninstallCompletionWithOptions("zsh", opts); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if _, err := os.Stat(zshScript); !os.IsNotExist(err) {
+		t.Errorf("Expected the legacy zsh script to be removed, got %v", err)
+	}
+
+	if got := readFile(t, zshrc); !strings.Contains(got, "fpath=(~/.zsh/completion $fpath)") {
+		t.Errorf("Expected the shared fpath line to be kept, got:\n%s", got)
+	}
+
+	if err := os.Remove(other); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cli.UninstallCompletionWithOptions("zsh", opts); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if got := readFile(t, zshrc); got != "autoload -Uz compinit && compinit\n" {
+		t.Errorf("Expected only the compinit line to be kept, got:\n%s", got)
+	}
+
+	if !strings.Contains(out.String(), "Removed the legacy completion script: "+zshScript) {
+		t.Errorf("Expected the removal to be reported, got:\n%s", out.String())
+	}
+}
+
+func TestInstallFishCompletion(t *testing.T) {
+	cli := New().SetName("app")
+	opts, _ := testInstallOptions(t, nil)
+
+	if err := cli.InstallCompletionWithOptions("fish", opts); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	path := filepath.Join(opts.Home, ".config", "fish", "completions", "app.fish")
+	if !strings.HasPrefix(readFile(t, path), "# Fish completion for app") {
+		t.Errorf("Unexpected completion script")
+	}
+
+	if err := cli.UninstallCompletionWithOptions("fish", opts); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the completion script to be removed, got %v", err)
+	}
+}
+
+func TestInstallCompletionDryRun(t *testing.T) {
+	cli := New().SetName("app")
+	opts, out := testInstallOptions(t, nil)
+	opts.DryRun = true
+
+	if err := cli.InstallCompletionWithOptions("zsh", opts); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	for _, want := range []string{
+		"Would write the Zsh completion script to: " + filepath.Join(opts.Home, ".local", "share", "zsh", "site-functions", "_app"),
+		"Would add the completion block to: " + filepath.Join(opts.Home, ".zshrc"),
+		"# >>> goflag completion for app >>>",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected the plan to contain %q, got:\n%s", want, out.String())
+		}
+	}
+
+	if entries, _ := os.ReadDir(opts.Home); len(entries) != 0 {
+		t.Errorf("Expected no files to be written, got %v", entries)
+	}
+
+	// The completion command accepts --dry-run.
+	out.Reset()
+	cli.SetOutput(out)
+	t.Setenv("HOME", opts.Home)
+	subcmd, err := cli.Parse([]string{"app", "completion", "-s", "fish", "--install", "--dry-run"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if err := subcmd.Run(t.Context()); err != nil || !strings.Contains(out.String(), "Would write the Fish completion script") {
+		t.Errorf("Expected a dry run, got %q (%v)", out.String(), err)
+	}
+}
+
+func TestInstallSystemCompletion(t *testing.T) {
+	cli := New().SetName("app")
+	opts, _ := testInstallOptions(t, nil)
+	opts.Root = t.TempDir()
+	opts.System = true
+	opts.Dynamic = true
+
+	if err := cli.InstallCompletionWithOptions("bash", opts); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	path := filepath.Join(opts.Root, "usr", "share", "bash-completion", "completions", "app")
+	if !strings.Contains(readFile(t, path), "__complete") {
+		t.Errorf("Expected the dynamic completion script at %s", path)
+	}
+
+	if _, err := os.Stat(filepath.Join(opts.Home, ".bashrc")); !os.IsNotExist(err) {
+		t.Errorf("Expected no rc file for a system-wide install, got %v", err)
+	}
+
+	if err := cli.InstallCompletionWithOptions("powershell", opts); err == nil {
+		t.Errorf("Expected an error for an unsupported shell")
+	}
+}
+
+func TestSetBlock(t *testing.T) {
+	block := "# >>> b >>>\nx\n# <<< b <<<\n"
+	tests := []struct {
+		content, block, want string
+	}{
+		{"", "x", block},
+		{"a", "x", "a\n" + block},
+		{"a\n" + "# >>> b >>>\nold\n# <<< b <<<\n" + "c\n", "x", "a\n" + block + "c\n"},
+		{"a\n" + block + "c\n", "", "a\nc\n"},
+		{"a\n", "", "a\n"},
+		{"# >>> b >>>\nunterminated\n", "", "# >>> b >>>\nunterminated\n"},
+	}
+
+	for _, test := range tests {
+		if got := setBlock(test.content, "b", test.block); got != test.want {
+			t.Errorf("setBlock(%q, %q): expected %q, but got %q", test.content, test.block, test.want, got)
+		}
+	}
+}
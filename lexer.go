@@ -0,0 +1,105 @@
+package goflag
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// The kind of a command line token.
+type tokenKind int
+
+const (
+	tokenLong       tokenKind = iota // --name or --name=value
+	tokenShort                       // -n or -n=value
+	tokenCluster                     // -abc, -p8080 or a single-dash long flag like -name
+	tokenValue                       // the value of a flag in the next arg. e.g John in --name John
+	tokenTerminator                  // the -- terminator.
+	tokenPositional                  // anything else, a lone - and all args after the terminator.
+	tokenInvalid                     // a flag without a name. e.g --=x or -=x
+)
+
+// A token of the command line.
+type token struct {
+	kind     tokenKind
+	text     string // the arg as given.
+	name     string // the flag name or cluster without dashes and inline value.
+	value    string // the inline value after the first =, or the text of a value or positional.
+	hasValue bool   // a value was given inline with =. e.g --name=John
+}
+
+// A single-pass lexer of the command line. It never modifies the args.
+// Whether an arg is the value of a flag depends on the flag, so the parser
+// takes the next arg as a value with lexer.takeValue after a flag token.
+type lexer struct {
+	args       []string
+	pos        int
+	terminated bool // the -- terminator was seen.
+}
+
+// Create a lexer of the args after the program name.
+func newLexer(args []string) *lexer {
+	return &lexer{args: args}
+}
+
+// Returns the next token, false if there are no more args.
+func (l *lexer) next() (token, bool) {
+	if l.pos >= len(l.args) {
+		return token{}, false
+	}
+
+	arg := l.args[l.pos]
+	l.pos++
+	switch {
+	case l.terminated:
+		return token{kind: tokenPositional, text: arg, value: arg}, true
+	case arg == "--":
+		l.terminated = true
+		return token{kind: tokenTerminator, text: arg}, true
+	}
+	return lexArg(arg), true
+}
+
+// Classify a single arg before the terminator.
+func lexArg(arg string) token {
+	tok := token{text: arg}
+
+	var flag string
+	switch {
+	case strings.HasPrefix(arg, "--"):
+		tok.kind = tokenLong
+		flag = arg[2:]
+	case len(arg) > 1 && arg[0] == '-':
+		tok.kind = tokenShort
+		flag = arg[1:]
+	default:
+		tok.kind = tokenPositional
+		tok.value = arg
+		return tok
+	}
+
+	tok.name, tok.value, tok.hasValue = strings.Cut(flag, "=")
+	if tok.name == "" {
+		return token{kind: tokenInvalid, text: arg}
+	}
+
+	if tok.kind == tokenShort && utf8.RuneCountInString(tok.name) > 1 {
+		tok.kind = tokenCluster
+	}
+	return tok
+}
+
+// Returns the next arg without consuming it, false if there are no more
+// args or the terminator was seen.
+func (l *lexer) peek() (string, bool) {
+	if l.terminated || l.pos >= len(l.args) {
+		return "", false
+	}
+	return l.args[l.pos], true
+}
+
+// Consume the next arg as the value of a flag. Call peek first.
+func (l *lexer) takeValue() token {
+	arg := l.args[l.pos]
+	l.pos++
+	return token{kind: tokenValue, text: arg, value: arg}
+}
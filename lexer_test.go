@@ -0,0 +1,204 @@
+package goflag
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLexer(t *testing.T) {
+	args := []string{"--name=a=b", "--verbose", "-n", "-n=x", "-xvf", "-p=80", "x=y", "-", "", "--=x", "-=y", "--", "--name", "-x"}
+	want := []token{
+		{kind: tokenLong, text: "--name=a=b", name: "name", value: "a=b", hasValue: true},
+		{kind: tokenLong, text: "--verbose", name: "verbose"},
+		{kind: tokenShort, text: "-n", name: "n"},
+		{kind: tokenShort, text: "-n=x", name: "n", value: "x", hasValue: true},
+		{kind: tokenCluster, text: "-xvf", name: "xvf"},
+		{kind: tokenShort, text: "-p=80", name: "p", value: "80", hasValue: true},
+		{kind: tokenPositional, text: "x=y", value: "x=y"},
+		{kind: tokenPositional, text: "-", value: "-"},
+		{kind: tokenPositional, text: "", value: ""},
+		{kind: tokenInvalid, text: "--=x"},
+		{kind: tokenInvalid, text: "-=y"},
+		{kind: tokenTerminator, text: "--"},
+		{kind: tokenPositional, text: "--name", value: "--name"},
+		{kind: tokenPositional, text: "-x", value: "-x"},
+	}
+
+	lex := newLexer(args)
+	for i, expected := range want {
+		tok, ok := lex.next()
+		if !ok {
+			t.Fatalf("Expected token %d, but the lexer is done", i)
+		}
+
+		if tok != expected {
+			t.Errorf("Expected %+v, but got %+v", expected, tok)
+		}
+	}
+
+	if tok, ok := lex.next(); ok {
+		t.Errorf("Expected no more tokens, but got %+v", tok)
+	}
+
+	// The next arg is taken as a value regardless of its form.
+	lex = newLexer([]string{"--name", "--", "x"})
+	lex.next()
+	if next, ok := lex.peek(); !ok || next != "--" {
+		t.Fatalf("Expected to peek --, but got %q", next)
+	}
+
+	if tok := lex.takeValue(); tok.kind != tokenValue || tok.value != "--" || lex.terminated {
+		t.Errorf("Expected -- as a value, but got %+v", tok)
+	}
+}
+
+func TestParseDoesNotMutateArgv(t *testing.T) {
+	var query, name, file string
+	var verbose bool
+
+	cli := New()
+	cli.String("query", "q", &query, "Query")
+	cli.Bool("verbose", "v", &verbose, "Verbose")
+	cli.Arg("file", &file, "File")
+	cli.SubCommand("greet", "Greet", func() {}).
+		String("name", "n", &name, "Name")
+
+	argv := []string{"app", "--query=a=b", "-v", "greet", "--name=x=y", "-n", "k=v"}
+	original := slices.Clone(argv)
+
+	if _, err := cli.Parse(argv); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if query != "a=b" || name != "k=v" || !verbose {
+		t.Errorf("Expected query a=b, name k=v and verbose, got %q, %q and %v", query, name, verbose)
+	}
+
+	if !slices.Equal(argv, original) {
+		t.Errorf("Expected argv to be unchanged, but got %q", argv)
+	}
+
+	// A lone - is a positional argument, conventionally stdin.
+	if _, err := cli.Parse([]string{"app", "-"}); err != nil || file != "-" {
+		t.Errorf("Expected file -, got %q (%v)", file, err)
+	}
+}
+
+func TestParseEmptyFlagName(t *testing.T) {
+	for _, arg := range []string{"--=x", "-=y"} {
+		var output, name string
+
+		cli := New()
+		cli.String("output", "", &output, "Output")
+		cli.SubCommand("greet", "Greet", func() {}).
+			String("name", "", &name, "Name")
+
+		_, err := cli.Parse([]string{"app", arg})
+		if err == nil || err.Error() != "invalid flag : "+arg || output != "" {
+			t.Errorf("Expected invalid flag %s, got %q (%v)", arg, output, err)
+		}
+
+		_, err = cli.Parse([]string{"app", "greet", arg})
+		if err == nil || err.Error() != "invalid flag : "+arg || name != "" {
+			t.Errorf("Expected invalid flag %s in greet, got %q (%v)", arg, name, err)
+		}
+	}
+}
+
+func FuzzLexer(f *testing.F) {
+	for _, seed := range []string{
+		"--name=a=b -v",
+		"-xvf archive.tar -- -x",
+		"- -= --= -- --",
+		"-éè=x -\xff",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		args := strings.Split(input, " ")
+		original := slices.Clone(args)
+
+		// Alternate between tokens and values so every path is exercised.
+		lex := newLexer(args)
+		var texts []string
+		for i := 0; ; i++ {
+			if i%3 == 2 {
+				if _, ok := lex.peek(); ok {
+					texts = append(texts, lex.takeValue().text)
+					continue
+				}
+			}
+
+			tok, ok := lex.next()
+			if !ok {
+				break
+			}
+			texts = append(texts, tok.text)
+
+			if (tok.kind == tokenLong || tok.kind == tokenShort || tok.kind == tokenCluster) && tok.name == "" {
+				t.Errorf("Expected a flag name in %q", tok.text)
+			}
+
+			if tok.hasValue && !strings.HasSuffix(tok.text, "="+tok.value) {
+				t.Errorf("Inline value %q is not a suffix of %q", tok.value, tok.text)
+			}
+		}
+
+		if !slices.Equal(texts, original) || !slices.Equal(args, original) {
+			t.Errorf("Expected every arg exactly once and unchanged, got %q from %q", texts, original)
+		}
+	})
+}
+
+func FuzzParse(f *testing.F) {
+	for _, seed := range []string{
+		"--query=a=b -vvv greet -n John",
+		"-vq x -- -v",
+		"-p8080 -l a=1,b=2 --no-color",
+		"greet --name= -",
+		"-q -v=x --count=-1",
+		"--=x -=y greet -=z",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		var (
+			query, name string
+			output      string
+			verbose     int
+			color       = true
+			port        int
+			labels      map[string]string
+			files       []string
+		)
+
+		cli := New()
+		cli.String("query", "q", &query, "Query")
+		cli.Count("verbose", "v", &verbose, "Verbosity")
+		cli.Bool("color", "c", &color, "Color").Negatable()
+		cli.Int("port", "p", &port, "Port")
+		cli.String("output", "", &output, "Output")
+		cli.StringMap("label", "l", &labels, "Labels")
+		cli.Arg("files", &files, "Files")
+		cli.SubCommand("greet", "Greet", func() {}).
+			String("name", "n", &name, "Name")
+		cli.SetOutput(&strings.Builder{})
+
+		argv := append([]string{"app"}, strings.Split(input, " ")...)
+		original := slices.Clone(argv)
+
+		// Errors are expected, panics and mutations are not.
+		_, _ = cli.Parse(argv)
+		if !slices.Equal(argv, original) {
+			t.Errorf("Expected argv to be unchanged, but got %q", argv)
+		}
+
+		// A flag without a short name is only set by its name.
+		if output != "" && !strings.Contains(input, "output") {
+			t.Errorf("Expected output to be unset by %q, but got %q", input, output)
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package goflag
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// DuplicateKeyPolicy decides what happens when a key of a map flag is given
+// more than once in a Parse. See Flag.OnDuplicateKey.
+type DuplicateKeyPolicy int
+
+const (
+	DuplicateKeyOverwrite DuplicateKeyPolicy = iota // the last value wins.
+	DuplicateKeyKeepFirst                           // the first value wins.
+	DuplicateKeyError                               // a repeated key is an error.
+)
+
+// Element types used to parse the values of map flags.
+var mapElemType = map[flagType]flagType{
+	flagStringMap:   flagString,
+	flagIntMap:      flagInt,
+	flagDurationMap: flagDuration,
+}
+
+// OnDuplicateKey sets the policy for keys of a map flag that are given more
+// than once, in one value or in repeated flags. The default is DuplicateKeyOverwrite.
+// It panics if the flag is not a map flag.
+func (flag *Flag) OnDuplicateKey(policy DuplicateKeyPolicy) *Flag {
+	flag.mustBeMap("OnDuplicateKey")
+	flag.keyPolicy = policy
+	return flag
+}
+
+// ValidateValues adds validators that are called with each value of a map flag.
+// e.g ValidateValues(Min(1)) for a map[string]int.
+// It panics if the flag is not a map flag.
+func (flag *Flag) ValidateValues(validators ...FlagValidator) *Flag {
+	flag.mustBeMap("ValidateValues")
+	flag.valueValidators = append(flag.valueValidators, validators...)
+	return flag
+}
+
+// OnDuplicateKey sets the duplicate key policy of the last flag in the
+// subcommand chain. See Flag.OnDuplicateKey.
+func (cmd *subcommand) OnDuplicateKey(policy DuplicateKeyPolicy) *subcommand {
+	if flag := cmd.lastFlag(); flag != nil {
+		flag.OnDuplicateKey(policy)
+	}
+	return cmd
+}
+
+// ValidateValues adds validators for each value of the last flag in the
+// subcommand chain. See Flag.ValidateValues.
+func (cmd *subcommand) ValidateValues(validators ...FlagValidator) *subcommand {
+	if flag := cmd.lastFlag(); flag != nil {
+		flag.ValidateValues(validators...)
+	}
+	return cmd
+}
+
+func (flag *Flag) mustBeMap(option string) {
+	if _, ok := mapElemType[flag.flagType]; !ok {
+		panic(fmt.Errorf("%s can only be used with map flags, flag %s is a %s", option, flag.name, flag.flagType))
+	}
+}
+
+// Parse the key=value pairs of a map flag. The pairs replace the current
+// value, or are merged into it if merging is true. The value is unchanged on error.
+func setMapValue(flag *Flag, value string, merging bool) error {
+	pairs, err := flag.splitValue(value)
+	if err != nil {
+		return err
+	}
+
+	current := reflect.ValueOf(flag.value).Elem()
+	result := reflect.MakeMap(current.Type())
+	if merging {
+		iter := current.MapRange()
+		for iter.Next() {
+			result.SetMapIndex(iter.Key(), iter.Value())
+		}
+	}
+
+	for _, pair := range pairs {
+		key, elem, err := parseMapPair(flag, pair)
+		if err != nil {
+			return err
+		}
+
+		if result.MapIndex(key).IsValid() {
+			switch flag.keyPolicy {
+			case DuplicateKeyKeepFirst:
+				continue
+			case DuplicateKeyError:
+				return fmt.Errorf("duplicate key %s", key)
+			}
+		}
+		result.SetMapIndex(key, elem)
+	}
+	current.Set(result)
+	return nil
+}
+
+// Parse and validate a key=value pair of a map flag.
+func parseMapPair(flag *Flag, pair string) (reflect.Value, reflect.Value, error) {
+	key, value, ok := strings.Cut(pair, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return reflect.Value{}, reflect.Value{}, fmt.Errorf("expected key=value, got %q", pair)
+	}
+
+	elem := reflect.New(reflect.TypeOf(flag.value).Elem().Elem())
+	tmp := &Flag{flagType: mapElemType[flag.flagType], name: flag.name, value: elem.Interface()}
+	if err := parseFlagValue(tmp, strings.TrimSpace(value)); err != nil {
+		return reflect.Value{}, reflect.Value{}, fmt.Errorf("key %s: %w", key, err)
+	}
+
+	for _, validator := range flag.valueValidators {
+		if validator == nil {
+			continue
+		}
+
+		if valid, errMsg := validator(elem.Elem().Interface()); !valid {
+			return reflect.Value{}, reflect.Value{}, fmt.Errorf("key %s: invalid value (%v): %v", key, elem.Elem(), errMsg)
+		}
+	}
+	return reflect.ValueOf(key), elem.Elem(), nil
+}
+
+// Parse comma-separated key=value pairs into a map of strings.
+// Pairs may be quoted like the elements of ParseStringSlice.
+// A repeated key takes the last value. e.g env=prod,team=core
+func ParseStringMap(value string) (map[string]string, error) {
+	pairs, err := splitList(value, ',')
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("expected key=value, got %q", pair)
+		}
+		result[key] = strings.TrimSpace(value)
+	}
+	return result, nil
+}
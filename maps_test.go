@@ -0,0 +1,144 @@
+package goflag
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMapFlags(t *testing.T) {
+	var labels map[string]string
+	var limits map[string]int
+	timeouts := map[string]time.Duration{"read": time.Second}
+
+	cli := New()
+	cli.StringMap("label", "l", &labels, "Labels")
+	cli.IntMap("set", "s", &limits, "Limits").ValidateValues(Min(1))
+	cli.DurationMap("timeout", "t", &timeouts, "Timeouts").Env("MAP_TIMEOUTS")
+
+	argv := []string{
+		"app", "--label", "env=prod", "-l", `team=core,"desc=a, b"`,
+		"--set", "a=1,b=2", "-s", "b=3",
+	}
+	if _, err := cli.Parse(argv); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	want := map[string]any{
+		"labels":   map[string]string{"env": "prod", "team": "core", "desc": "a, b"},
+		"limits":   map[string]int{"a": 1, "b": 3},
+		"timeouts": map[string]time.Duration{"read": time.Second},
+	}
+	got := map[string]any{"labels": labels, "limits": limits, "timeouts": timeouts}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, but got %v", want, got)
+	}
+
+	// The first occurrence replaces the default.
+	t.Setenv("MAP_TIMEOUTS", "write=5s")
+	if _, err := cli.Parse([]string{"app", "-t", "dial=1s", "-t", "idle=1m"}); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	wantTimeouts := map[string]time.Duration{"dial": time.Second, "idle": time.Minute}
+	if !reflect.DeepEqual(timeouts, wantTimeouts) {
+		t.Errorf("Expected %v, but got %v", wantTimeouts, timeouts)
+	}
+
+	if _, err := cli.Parse([]string{"app"}); err != nil || !reflect.DeepEqual(timeouts, map[string]time.Duration{"write": 5 * time.Second}) {
+		t.Errorf("Expected timeouts from env, got %v (%v)", timeouts, err)
+	}
+
+	invalid := []struct {
+		argv []string
+		want string
+	}{
+		{[]string{"app", "-l", "env"}, `expected key=value, got "env"`},
+		{[]string{"app", "-l", "=prod"}, `expected key=value, got "=prod"`},
+		{[]string{"app", "-s", "a=x"}, "key a: invalid int value x"},
+		{[]string{"app", "-s", "a=0"}, "key a: invalid value (0)"},
+	}
+
+	for _, test := range invalid {
+		_, err := cli.Parse(test.argv)
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%v: expected error %q, but got %v", test.argv, test.want, err)
+		}
+	}
+
+	// Arrays of pairs in the configuration file replace the default.
+	cli.ConfigFile(writeConfig(t, "app.json", `{"label": ["env=dev", "team=web"]}`))
+	if _, err := cli.Parse([]string{"app"}); err != nil || !reflect.DeepEqual(labels, map[string]string{"env": "dev", "team": "web"}) {
+		t.Errorf("Expected labels from config, got %v (%v)", labels, err)
+	}
+
+	var out bytes.Buffer
+	cli.PrintUsage(&out)
+	if !strings.Contains(out.String(), "Timeouts (default: write=5s, repeatable)") {
+		t.Errorf("Expected map default in help, got:\n%s", out.String())
+	}
+}
+
+func TestMapDuplicateKeys(t *testing.T) {
+	var first, strict map[string]string
+
+	cli := New()
+	cli.SubCommand("run", "Run", func() {}).
+		StringMap("first", "f", &first, "First wins").OnDuplicateKey(DuplicateKeyKeepFirst).
+		StringMap("strict", "s", &strict, "No duplicates").OnDuplicateKey(DuplicateKeyError)
+
+	if _, err := cli.Parse([]string{"app", "run", "-f", "a=1,a=2", "-f", "a=3,b=4", "-s", "a=1", "-s", "b=2"}); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if !reflect.DeepEqual(first, map[string]string{"a": "1", "b": "4"}) {
+		t.Errorf("Expected first value to win, got %v", first)
+	}
+
+	for _, argv := range [][]string{{"app", "run", "-s", "a=1,a=2"}, {"app", "run", "-s", "a=1", "-s", "a=2"}} {
+		_, err := cli.Parse(argv)
+		if err == nil || err.Error() != "duplicate key a" {
+			t.Errorf("%v: expected duplicate key error, but got %v", argv, err)
+		}
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Expected panic for OnDuplicateKey on a slice flag")
+		}
+	}()
+	var tags []string
+	cli.StringSlice("tags", "t", &tags, "Tags").OnDuplicateKey(DuplicateKeyError)
+}
+
+func TestMapValueSet(t *testing.T) {
+	var limits map[string]int
+
+	cli := New()
+	flag := cli.IntMap("set", "s", &limits, "Limits").
+		Separator(';').OnDuplicateKey(DuplicateKeyError).ValidateValues(Min(1))
+
+	// Value().Set parses like the command line.
+	v := flag.Value()
+	if err := v.Set("a=1;b=2"); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if !reflect.DeepEqual(limits, map[string]int{"a": 1, "b": 2}) || v.String() != "a=1;b=2" || v.Type() != "key=int" {
+		t.Errorf("Expected key=int a=1;b=2, but got %s %q (%v)", v.Type(), v.String(), limits)
+	}
+
+	if err := v.Set("a=1;a=2"); err == nil || err.Error() != "duplicate key a" {
+		t.Errorf("Expected duplicate key error, but got %v", err)
+	}
+
+	if err := v.Set("a=0"); err == nil || !strings.Contains(err.Error(), "key a") {
+		t.Errorf("Expected validator error for key a, but got %v", err)
+	}
+
+	if !reflect.DeepEqual(limits, map[string]int{"a": 1, "b": 2}) {
+		t.Errorf("Expected the value to be unchanged on error, but got %v", limits)
+	}
+}
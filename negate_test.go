@@ -0,0 +1,86 @@
+package goflag
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestNegatableFlags(t *testing.T) {
+	color, cache := true, true
+	var verbose bool
+
+	newCLI := func() *CLI {
+		color, cache, verbose = true, true, false
+		cli := New()
+		cli.Bool("color", "c", &color, "Colorize output").Negatable()
+		cli.Bool("verbose", "v", &verbose, "Verbose output")
+		cli.SubCommand("build", "Build", func() {}).
+			Bool("cache", "", &cache, "Use the build cache").Negatable()
+		return cli
+	}
+
+	cli := newCLI()
+	if _, err := cli.Parse([]string{"app", "--no-color", "build", "--no-cache"}); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if color || cache {
+		t.Errorf("Expected color and cache to be false, got %v and %v", color, cache)
+	}
+
+	if flag := cli.Lookup("color"); flag.Source() != SourceArgv {
+		t.Errorf("Expected negated flag from argv, got %s", flag.Source())
+	}
+
+	// The last occurrence wins.
+	if _, err := newCLI().Parse([]string{"app", "--no-color", "--color"}); err != nil || !color {
+		t.Errorf("Expected color to be true, got %v (%v)", color, err)
+	}
+
+	invalid := []struct {
+		argv []string
+		want string
+	}{
+		{[]string{"app", "--no-color=true"}, "flag --no-color does not take a value"},
+		{[]string{"app", "--no-verbose"}, "unknown flag : no-verbose"},
+	}
+
+	for _, test := range invalid {
+		_, err := newCLI().Parse(test.argv)
+		if err == nil || err.Error() != test.want {
+			t.Errorf("%v: expected error %q, but got %v", test.argv, test.want, err)
+		}
+	}
+
+	var out bytes.Buffer
+	cli = newCLI()
+	cli.PrintUsage(&out)
+	for _, want := range []string{"--[no-]color -c: Colorize output", "--[no-]cache Use the build cache"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected help to contain %q, got:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	cli.GenBashCompletion(&out)
+	if !strings.Contains(out.String(), "--color --no-color --verbose") {
+		t.Errorf("Expected negated flag in bash completions, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	cli.GenZshCompletion(&out)
+	for _, want := range []string{"'(--no-color)--color[Colorize output]'", "'(--color)--no-color[Turn off --color]'"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected zsh completions to contain %q, got:\n%s", want, out.String())
+		}
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Expected panic for a negatable string flag")
+		}
+	}()
+	var name string
+	cli.String("name", "n", &name, "Name").Negatable()
+}
@@ -14,143 +14,13 @@ import (
 	"github.com/google/uuid"
 )
 
-// Returns the converted value from string based on flag type.
+// Parse the string into the value of the flag.
 func parseFlagValue(flag *Flag, value string) error {
-	switch flag.flagType {
-	case flagString:
-		*flag.value.(*string) = value
-		return nil
-	case flagInt:
-		intValue, err := ParseInt(value)
-		if err != nil {
-			return err
-		}
-		*flag.value.(*int) = intValue
-		return nil
-	case flagInt64:
-		int64Value, err := ParseInt64(value)
-		if err != nil {
-			return err
-		}
-		*flag.value.(*int64) = int64Value
-		return nil
-	case flagFloat32:
-		float32Value, err := ParseFloat32(value)
-		if err != nil {
-			return err
-		}
-		*flag.value.(*float32) = float32Value
-		return nil
-	case flagFloat64:
-		float64Value, err := ParseFloat64(value)
-		if err != nil {
-			return err
-		}
-		*flag.value.(*float64) = float64Value
-		return nil
-	case flagBool:
-		boolValue, err := ParseBool(value)
-		if err != nil {
-			return err
-		}
-		*flag.value.(*bool) = boolValue
-		return nil
-	case flagStringSlice:
-		stringSliceValue, err := ParseStringSlice(value)
-		if err != nil {
-			return err
-		}
-		*flag.value.(*[]string) = stringSliceValue
-		return nil
-
-	case flagIntSlice:
-		intSliceValue, err := ParseIntSlice(value)
-		if err != nil {
-			return err
-		}
-		*flag.value.(*[]int) = intSliceValue
-		return nil
-	case flagRune:
-		runeValue, err := ParseRune(value)
-		if err != nil {
-			return err
-		}
-		*flag.value.(*rune) = runeValue
-		return nil
-	case flagDuration:
-		durationValue, err := ParseDuration(value)
-		if err != nil {
-			return err
-		}
-		*flag.value.(*time.Duration) = durationValue
-		return nil
-	case flagTime:
-		timeValue, err := ParseTime(value)
-		if err != nil {
-			return err
-		}
-		*flag.value.(*time.Time) = timeValue
-		return nil
-	case flagIP:
-		ipValue, err := ParseIP(value)
-		if err != nil {
-			return err
-		}
-		*flag.value.(*net.IP) = ipValue
-		return nil
-	case flagFilePath:
-		filePath, err := ParseFilePath(value)
-		if err != nil {
-			return err
-		}
-		*flag.value.(*string) = filePath
-		return nil
-	case flagDirPath:
-		dirPath, err := ParseDirPath(value)
-		if err != nil {
-			return err
-		}
-		*flag.value.(*string) = dirPath
-		return nil
-	case flagEmail:
-		email, err := ParseEmail(value)
-		if err != nil {
-			return err
-		}
-		*flag.value.(*string) = email
-		return nil
-	case flagURL:
-		uri, err := ParseUrl(value)
-		if err != nil {
-			return err
-		}
-		*flag.value.(*url.URL) = *uri
-		return nil
-	case flagUUID:
-		uuidValue, err := ParseUUID(value)
-		if err != nil {
-			return err
-		}
-		*flag.value.(*uuid.UUID) = uuidValue
-		return nil
-	case flagHostPortPair:
-		hostPortPair, err := ParseHostPort(value)
-		if err != nil {
-			return err
-		}
-		*flag.value.(*string) = hostPortPair
-		return nil
-	case flagMAC:
-		mac, err := ParseMAC(value)
-		if err != nil {
-			return err
-		}
-		*flag.value.(*net.HardwareAddr) = mac
-		return nil
-
+	v := flag.Value()
+	if v == nil {
+		return fmt.Errorf("unsupported value %T for flag type %s", flag.value, flag.flagType.String())
 	}
-
-	return fmt.Errorf("unsupported flag type %s", flag.flagType.String())
+	return v.Set(value)
 }
 
 // Parse a string to an int.
@@ -204,18 +74,20 @@ func ParseBool(value string) (bool, error) {
 }
 
 // Parse a comma-seperated string into a slice of strings.
+// Elements are trimmed of surrounding whitespace. Elements may be quoted
+// CSV-style to contain commas, with "" for a literal quote.
+// e.g a, "b, c", "say ""hi"""
 func ParseStringSlice(value string) ([]string, error) {
-	parts := strings.Split(value, ",")
-	result := make([]string, len(parts))
-	for index := range parts {
-		result[index] = strings.TrimSpace(parts[index])
-	}
-	return result, nil
+	return splitList(value, ',')
 }
 
 // Parse a comma-seperated string into a slice of ints.
 func ParseIntSlice(value string) ([]int, error) {
-	parts := strings.Split(value, ",")
+	parts, err := splitList(value, ',')
+	if err != nil {
+		return nil, err
+	}
+
 	result := make([]int, len(parts))
 	for index := range parts {
 		intvalue, err := ParseInt(strings.TrimSpace(parts[index]))
@@ -0,0 +1,122 @@
+package goflag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Exit codes used by Execute.
+const (
+	ExitOK          = 0   // The command completed successfully.
+	ExitFailure     = 1   // The handler returned an error.
+	ExitUsage       = 2   // The command line could not be parsed.
+	ExitInterrupted = 130 // The context was canceled by SIGINT or SIGTERM.
+)
+
+// ExitError is an error that carries the exit code of the process.
+// Return it from a handler to control the exit code used by Execute.
+type ExitError struct {
+	Code int   // Exit code of the process.
+	Err  error // Underlying error. May be nil to exit silently.
+}
+
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode maps an error returned by Run to an exit code.
+// nil and ErrHelpRequested map to ExitOK, an *ExitError to its code,
+// a canceled context to ExitInterrupted and any other error to ExitFailure.
+func ExitCode(err error) int {
+	if err == nil || errors.Is(err, ErrHelpRequested) {
+		return ExitOK
+	}
+
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return ExitInterrupted
+	}
+	return ExitFailure
+}
+
+// Run parses argv and invokes the handler of the matched subcommand with ctx.
+// argv should be os.Args, the first argument is the program name.
+//
+// Parse errors are returned as an *ExitError with code ExitUsage.
+// ErrHelpRequested is returned as is after the usage is printed.
+// If no subcommand matches, Run returns nil after parsing.
+func (c *CLI) Run(ctx context.Context, argv []string) error {
+	subcmd, err := c.Parse(argv)
+	if errors.Is(err, ErrHelpRequested) {
+		return err
+	}
+
+	if err != nil {
+		return &ExitError{Code: ExitUsage, Err: err}
+	}
+
+	if subcmd == nil {
+		return nil
+	}
+	return subcmd.Run(ctx)
+}
+
+// Execute runs the CLI with os.Args and exits the process through the
+// exit function of the CLI, see SetExitFunc.
+// The context passed to handlers is canceled on SIGINT or SIGTERM.
+// Errors are printed to the error writer and mapped to an exit code with ExitCode.
+func (c *CLI) Execute() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := c.Run(ctx, os.Args)
+	stop()
+	c.exit(err)
+}
+
+// Print err to the error writer and exit with the mapped exit code.
+// Does nothing if err is nil or ErrHelpRequested.
+func (c *CLI) exit(err error) {
+	if code := c.report(err); code != ExitOK {
+		c.exitFn()(code)
+	}
+}
+
+// Print err to the error writer and return the mapped exit code.
+// Nothing is printed if err is nil, ErrHelpRequested or an ExitError
+// without an error.
+func (c *CLI) report(err error) int {
+	code := ExitCode(err)
+	if code == ExitOK {
+		return code
+	}
+
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Err != nil {
+		fmt.Fprintln(c.stderr(), err)
+	}
+	return code
+}
+
+// Report the error of a context-aware handler called through the Handler
+// field. Only an exit function set with SetExitFunc is called, so that
+// calling Handler from library code never ends the process.
+func (c *CLI) handlerExit(err error) {
+	code := c.report(err)
+	if code != ExitOK && c != nil && c.exitFunc != nil {
+		c.exitFunc(code)
+	}
+}
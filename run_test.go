@@ -0,0 +1,164 @@
+package goflag
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	cli := New()
+
+	var (
+		name   string
+		called *Command
+		gotCtx context.Context
+	)
+
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+
+	greet := cli.Command("greet", "Greet a person", func(ctx context.Context, cmd *Command) error {
+		called, gotCtx = cmd, ctx
+		return nil
+	}).String("name", "n", &name, "Name").Required()
+
+	if err := cli.Run(ctx, []string{"myapp", "greet", "-n", "John"}); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if called != greet || name != "John" {
+		t.Errorf("Expected greet handler to be called with the command, got %v", called)
+	}
+
+	if gotCtx.Value(ctxKey{}) != "value" {
+		t.Errorf("Expected handler to receive the context passed to Run")
+	}
+
+	// Parse errors are usage errors.
+	err := cli.Run(ctx, []string{"myapp", "greet"})
+	if ExitCode(err) != ExitUsage {
+		t.Errorf("Expected exit code %d for parse error, but got %d (%v)", ExitUsage, ExitCode(err), err)
+	}
+
+	// No subcommand, nothing to dispatch.
+	if err := cli.Run(ctx, []string{"myapp"}); err != nil {
+		t.Errorf("Expected no error without subcommand, but got '%v'", err)
+	}
+}
+
+func TestRunHandlerErrors(t *testing.T) {
+	cli := New()
+	errFailed := errors.New("failed")
+
+	legacyCalled := false
+	cli.SubCommand("legacy", "Plain handler", func() { legacyCalled = true })
+	cli.Command("fail", "Failing handler", func(ctx context.Context, cmd *Command) error {
+		return errFailed
+	})
+	cli.Command("exit", "Exit with code", func(ctx context.Context, cmd *Command) error {
+		return &ExitError{Code: 3, Err: errFailed}
+	})
+	cli.Command("wait", "Wait for cancellation", func(ctx context.Context, cmd *Command) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}).Command("child", "Nested handler", func(ctx context.Context, cmd *Command) error {
+		return &ExitError{Code: 4}
+	})
+
+	ctx := context.Background()
+	if err := cli.Run(ctx, []string{"myapp", "legacy"}); err != nil || !legacyCalled {
+		t.Errorf("Expected plain handler to be called without error, got %v", err)
+	}
+
+	err := cli.Run(ctx, []string{"myapp", "fail"})
+	if !errors.Is(err, errFailed) || ExitCode(err) != ExitFailure {
+		t.Errorf("Expected handler error with exit code %d, got %v (%d)", ExitFailure, err, ExitCode(err))
+	}
+
+	err = cli.Run(ctx, []string{"myapp", "exit"})
+	if !errors.Is(err, errFailed) || ExitCode(err) != 3 {
+		t.Errorf("Expected exit code 3, got %v (%d)", err, ExitCode(err))
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	err = cli.Run(canceled, []string{"myapp", "wait"})
+	if ExitCode(err) != ExitInterrupted {
+		t.Errorf("Expected exit code %d for canceled context, got %v (%d)", ExitInterrupted, err, ExitCode(err))
+	}
+
+	err = cli.Run(ctx, []string{"myapp", "wait", "child"})
+	if ExitCode(err) != 4 {
+		t.Errorf("Expected exit code 4 from nested handler, got %v (%d)", err, ExitCode(err))
+	}
+
+	if ExitCode(nil) != ExitOK {
+		t.Errorf("Expected exit code %d for nil error", ExitOK)
+	}
+}
+
+func TestHelpAndOutput(t *testing.T) {
+	var out, errOut bytes.Buffer
+	exitCode := -1
+
+	cli := New().SetOutput(&out).SetErrOutput(&errOut).SetExitFunc(func(code int) { exitCode = code })
+	cli.Command("fail", "Failing handler", func(ctx context.Context, cmd *Command) error {
+		return errors.New("boom")
+	})
+
+	_, err := cli.Parse([]string{"myapp", "--help"})
+	if !errors.Is(err, ErrHelpRequested) {
+		t.Fatalf("Expected ErrHelpRequested, but got %v", err)
+	}
+
+	if !strings.Contains(out.String(), "Global Flags:") {
+		t.Errorf("Expected usage to be written to the output writer, got %q", out.String())
+	}
+
+	out.Reset()
+	if err := cli.Run(context.Background(), []string{"myapp", "fail", "-h"}); !errors.Is(err, ErrHelpRequested) || ExitCode(err) != ExitOK {
+		t.Errorf("Expected ErrHelpRequested with exit code 0, but got %v", err)
+	}
+
+	if !strings.Contains(out.String(), "Failing handler") {
+		t.Errorf("Expected subcommand usage to be written to the output writer, got %q", out.String())
+	}
+
+	// The completion command writes to the output writer.
+	out.Reset()
+	if err := cli.Run(context.Background(), []string{"myapp", "completion", "--shell", "bash"}); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if !strings.Contains(out.String(), "complete -F") {
+		t.Errorf("Expected bash completion script in output writer")
+	}
+
+	err = cli.Run(context.Background(), []string{"myapp", "completion", "-s", "zsh", "--install", "--uninstall"})
+	if err == nil {
+		t.Errorf("Expected error for --install with --uninstall")
+	}
+
+	// The Handler of a context-aware subcommand reports errors through the exit hook.
+	subcmd, err := cli.Parse([]string{"myapp", "fail"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+	subcmd.Handler()
+
+	if exitCode != ExitFailure || !strings.Contains(errOut.String(), "boom") {
+		t.Errorf("Expected exit hook with code %d and error output, got %d %q", ExitFailure, exitCode, errOut.String())
+	}
+
+	// Without an exit hook, the Handler only prints the error and returns.
+	errOut.Reset()
+	cli.exitFunc = nil
+	subcmd.Handler()
+
+	if !strings.Contains(errOut.String(), "boom") {
+		t.Errorf("Expected error output without an exit hook, got %q", errOut.String())
+	}
+}
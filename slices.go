@@ -0,0 +1,198 @@
+package goflag
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// Append makes repeated occurrences of a slice flag on the command line
+// accumulate instead of replacing each other. The first occurrence replaces
+// the default. e.g --header "A: 1" --header "B: 2"
+// Map flags always accumulate.
+// It panics if the flag is not a slice or map flag.
+func (flag *Flag) Append() *Flag {
+	flag.mustBeList("Append")
+	flag.appendValues = true
+	return flag
+}
+
+// Separator sets the separator of the elements of a slice flag or the pairs
+// of a map flag. The default is ','. Elements may be quoted CSV-style to
+// contain the separator. See ParseStringSlice.
+// It panics if the flag is not a slice or map flag.
+func (flag *Flag) Separator(sep rune) *Flag {
+	flag.mustBeList("Separator")
+	flag.separator = sep
+	return flag
+}
+
+// NoSplit makes each value of a slice flag a single element, or of a map flag
+// a single key=value pair, so values may contain commas without quoting.
+// Combine it with Append to collect values from repeated flags.
+// It panics if the flag is not a slice or map flag.
+func (flag *Flag) NoSplit() *Flag {
+	flag.mustBeList("NoSplit")
+	flag.noSplit = true
+	return flag
+}
+
+// Append makes repeats of the last flag in the subcommand chain accumulate.
+// See Flag.Append.
+func (cmd *subcommand) Append() *subcommand {
+	if flag := cmd.lastFlag(); flag != nil {
+		flag.Append()
+	}
+	return cmd
+}
+
+// Separator sets the separator of the last flag in the subcommand chain.
+// See Flag.Separator.
+func (cmd *subcommand) Separator(sep rune) *subcommand {
+	if flag := cmd.lastFlag(); flag != nil {
+		flag.Separator(sep)
+	}
+	return cmd
+}
+
+// NoSplit disables splitting the values of the last flag in the subcommand chain.
+// See Flag.NoSplit.
+func (cmd *subcommand) NoSplit() *subcommand {
+	if flag := cmd.lastFlag(); flag != nil {
+		flag.NoSplit()
+	}
+	return cmd
+}
+
+func (flag *Flag) mustBeList(option string) {
+	_, isSlice := sliceElemType[flag.flagType]
+	_, isMap := mapElemType[flag.flagType]
+	if !isSlice && !isMap {
+		panic(fmt.Errorf("%s can only be used with slice and map flags, flag %s is a %s", option, flag.name, flag.flagType))
+	}
+}
+
+// Reports whether repeated values on the command line accumulate.
+func (flag *Flag) accumulates() bool {
+	_, isMap := mapElemType[flag.flagType]
+	return flag.appendValues || isMap
+}
+
+// Reports whether the flag may be repeated on the command line.
+func (flag *Flag) repeatable() bool {
+	return flag.flagType == flagCount || flag.accumulates()
+}
+
+// Split the value of a slice flag into its elements.
+func (flag *Flag) splitValue(value string) ([]string, error) {
+	if flag.noSplit {
+		return []string{value}, nil
+	}
+
+	sep := flag.separator
+	if sep == 0 {
+		sep = ','
+	}
+	return splitList(value, sep)
+}
+
+// Parse the value of a slice flag. The elements replace the current value,
+// or are appended to it if appending is true. The value is unchanged on error.
+func setSliceValue(flag *Flag, value string, appending bool) error {
+	elems, err := flag.splitValue(value)
+	if err != nil {
+		return err
+	}
+
+	current := reflect.ValueOf(flag.value).Elem()
+	slice := reflect.Zero(current.Type())
+	if appending {
+		slice = current
+	}
+
+	for _, elem := range elems {
+		v, err := parseSliceElem(flag, elem)
+		if err != nil {
+			return err
+		}
+		slice = reflect.Append(slice, v)
+	}
+	current.Set(slice)
+	return nil
+}
+
+// Parse a single element of a slice flag or variadic argument.
+func parseSliceElem(flag *Flag, value string) (reflect.Value, error) {
+	elem := reflect.New(reflect.TypeOf(flag.value).Elem().Elem())
+	tmp := &Flag{flagType: sliceElemType[flag.flagType], name: flag.name, value: elem.Interface()}
+	if err := parseFlagValue(tmp, value); err != nil {
+		return reflect.Value{}, err
+	}
+	return elem.Elem(), nil
+}
+
+// Parse a value given on the command line. Repeated values of map flags
+// and slice flags in append mode accumulate.
+func parseArgvValue(flag *Flag, value string) error {
+	if !flag.accumulates() || !flag.Changed() {
+		return parseFlagValue(flag, value)
+	}
+
+	if _, ok := mapElemType[flag.flagType]; ok {
+		return setMapValue(flag, value, true)
+	}
+	return setSliceValue(flag, value, true)
+}
+
+// Split a list on sep, CSV-style. Elements are trimmed of surrounding
+// whitespace. An element in double quotes may contain sep and whitespace,
+// and "" inside the quotes is a literal quote. e.g a, "b, c", "say ""hi"""
+func splitList(value string, sep rune) ([]string, error) {
+	var elems []string
+	var elem strings.Builder
+	inQuotes, quoted := false, false
+
+	finish := func() {
+		s := elem.String()
+		if !quoted {
+			s = strings.TrimSpace(s)
+		}
+		elems = append(elems, s)
+		elem.Reset()
+		quoted = false
+	}
+
+	runes := []rune(value)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case inQuotes:
+			if r != '"' {
+				elem.WriteRune(r)
+			} else if i+1 < len(runes) && runes[i+1] == '"' {
+				elem.WriteRune('"')
+				i++
+			} else {
+				inQuotes = false
+			}
+		case r == sep:
+			finish()
+		case quoted:
+			if !unicode.IsSpace(r) {
+				return nil, fmt.Errorf("invalid list value %s: unexpected %q after quoted element", value, r)
+			}
+		case r == '"' && strings.TrimSpace(elem.String()) == "":
+			elem.Reset()
+			inQuotes, quoted = true, true
+		default:
+			elem.WriteRune(r)
+		}
+	}
+
+	if inQuotes {
+		return nil, fmt.Errorf("invalid list value %s: unterminated quoted element", value)
+	}
+	finish()
+	return elems, nil
+}
@@ -0,0 +1,126 @@
+package goflag
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseStringSliceQuoting(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"a,b", []string{"a", "b"}},
+		{" a , b ", []string{"a", "b"}},
+		{`a, "b, c"`, []string{"a", "b, c"}},
+		{`" padded ",x`, []string{" padded ", "x"}},
+		{`"say ""hi"""`, []string{`say "hi"`}},
+		{`5" screen,x`, []string{`5" screen`, "x"}},
+		{"", []string{""}},
+	}
+
+	for _, test := range tests {
+		got, err := ParseStringSlice(test.value)
+		if err != nil {
+			t.Errorf("%q: expected no error, but got '%v'", test.value, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: expected %q, but got %q", test.value, test.want, got)
+		}
+	}
+
+	for _, value := range []string{`"unterminated`, `"a"b,c`} {
+		if _, err := ParseStringSlice(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
+
+func TestSliceAppend(t *testing.T) {
+	var headers, tags, paths []string
+	ports := []int{80}
+
+	cli := New()
+	cli.StringSlice("header", "H", &headers, "Headers").Append().NoSplit()
+	cli.StringSlice("tag", "t", &tags, "Tags").Append()
+	cli.StringSlice("path", "p", &paths, "Paths").Separator(':')
+	cli.IntSlice("port", "P", &ports, "Ports").Append().Env("SLICE_PORTS")
+
+	argv := []string{
+		"app", "--header", "A: 1, 2", "-H", "B: 2",
+		"-t", "x,y", "--tag=z",
+		"-p", "/bin:/usr/bin", "-p", "/sbin",
+		"-P8080", "-P", "8081,8082",
+	}
+	if _, err := cli.Parse(argv); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	want := map[string]any{
+		"headers": []string{"A: 1, 2", "B: 2"},
+		"tags":    []string{"x", "y", "z"},
+		"paths":   []string{"/sbin"},
+		"ports":   []int{8080, 8081, 8082},
+	}
+	got := map[string]any{"headers": headers, "tags": tags, "paths": paths, "ports": ports}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, but got %v", want, got)
+	}
+
+	// Values from the environment replace the default and are split.
+	t.Setenv("SLICE_PORTS", "1,2")
+	if _, err := cli.Parse([]string{"app", "-p", "/a:/b"}); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if !reflect.DeepEqual(ports, []int{1, 2}) || !reflect.DeepEqual(paths, []string{"/a", "/b"}) {
+		t.Errorf("Expected ports [1 2] and paths [/a /b], got %v and %v", ports, paths)
+	}
+
+	// An invalid element leaves the value unchanged.
+	if _, err := cli.Parse([]string{"app", "-P", "1", "-P", "2,x"}); err == nil {
+		t.Errorf("Expected an error for an invalid port")
+	} else if !reflect.DeepEqual(ports, []int{1}) {
+		t.Errorf("Expected ports [1], but got %v", ports)
+	}
+
+	var out bytes.Buffer
+	cli.GenZshCompletion(&out)
+	if !strings.Contains(out.String(), "'*--header[Headers]:value:'") {
+		t.Errorf("Expected repeatable header flag in zsh completions, got:\n%s", out.String())
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Expected panic for Append on a string flag")
+		}
+	}()
+	var name string
+	cli.String("name", "n", &name, "Name").Append()
+}
+
+func TestSliceValueSet(t *testing.T) {
+	var headers, paths []string
+
+	cli := New()
+	header := cli.StringSlice("header", "H", &headers, "Headers").NoSplit()
+	path := cli.StringSlice("path", "p", &paths, "Paths").Separator(':')
+
+	// Value().Set parses like the command line.
+	if err := header.Value().Set("A: 1, 2"); err != nil || !reflect.DeepEqual(headers, []string{"A: 1, 2"}) {
+		t.Errorf("Expected a single header, got %q (%v)", headers, err)
+	}
+
+	v := path.Value()
+	if err := v.Set("/bin:/usr/bin"); err != nil || !reflect.DeepEqual(paths, []string{"/bin", "/usr/bin"}) {
+		t.Errorf("Expected paths [/bin /usr/bin], got %q (%v)", paths, err)
+	}
+
+	if v.String() != "/bin:/usr/bin" || v.Type() != "strings" {
+		t.Errorf("Expected strings /bin:/usr/bin, but got %s %q", v.Type(), v.String())
+	}
+}
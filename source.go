@@ -0,0 +1,88 @@
+package goflag
+
+// Source is where the value of a flag came from in the last Parse.
+type Source int
+
+const (
+	SourceDefault Source = iota // not set, the value is the default.
+	SourceArgv                  // given on the command line.
+	SourceEnv                   // read from an environment variable.
+	SourceConfig                // read from the configuration file.
+	SourcePrompt                // set by the application, e.g from an interactive prompt. See Flag.SetValue.
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourceDefault:
+		return "default"
+	case SourceArgv:
+		return "argv"
+	case SourceEnv:
+		return "env"
+	case SourceConfig:
+		return "config"
+	case SourcePrompt:
+		return "prompt"
+	}
+	return "unknown"
+}
+
+// Changed reports whether the flag was set in the last Parse, from any
+// source other than its default.
+func (flag *Flag) Changed() bool {
+	return flag.source != SourceDefault
+}
+
+// Source returns where the value of the flag came from in the last Parse.
+func (flag *Flag) Source() Source {
+	return flag.source
+}
+
+// SetValue parses and validates value like a command line value and records
+// source as the source of the flag. Use it to fill flags after Parse,
+// e.g with SourcePrompt for values read from an interactive prompt.
+func (flag *Flag) SetValue(value string, source Source) error {
+	if err := parseFlagValue(flag, value); err != nil {
+		return err
+	}
+
+	if err := validateFlagValue(flag); err != nil {
+		return err
+	}
+	flag.source = source
+	return nil
+}
+
+// Lookup returns the global flag or positional argument with the given
+// long or short name, or nil if there is none.
+func (c *CLI) Lookup(name string) *Flag {
+	if flag := findFlag(c.flags, name); flag != nil {
+		return flag
+	}
+	return findFlag(c.args, name)
+}
+
+// Lookup returns the flag or positional argument of the subcommand with the
+// given long or short name, including inherited flags, or nil if there is none.
+func (cmd *subcommand) Lookup(name string) *Flag {
+	if flag := findFlag(cmd.allFlags(), name); flag != nil {
+		return flag
+	}
+	return findFlag(cmd.args, name)
+}
+
+// Reset the sources of all flags and arguments before a Parse.
+func (c *CLI) resetSources() {
+	reset := func(flags []*Flag) {
+		for _, flag := range flags {
+			flag.source = SourceDefault
+		}
+	}
+
+	reset(c.flags)
+	reset(c.args)
+	walkSubCommands(c.subcommands, func(cmd *subcommand) {
+		reset(cmd.flags)
+		reset(cmd.args)
+	})
+}
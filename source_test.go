@@ -0,0 +1,78 @@
+package goflag
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFlagSource(t *testing.T) {
+	var port, workers, retries int
+	var host, name, file string
+
+	path := writeConfig(t, "app.json", `{"workers": 4}`)
+
+	cli := New().ConfigFile(path)
+	cli.Int("port", "p", &port, "Port")
+	cli.Int("workers", "w", &workers, "Workers")
+	cli.Int("retries", "r", &retries, "Retries").Validate(Min(1))
+	cli.String("host", "H", &host, "Host").Env("SOURCE_HOST")
+	cli.Arg("file", &file, "File")
+	cli.SubCommand("greet", "Greet", func() {}).
+		String("name", "n", &name, "Name")
+
+	t.Setenv("SOURCE_HOST", "example.com")
+	cmd, err := cli.Parse([]string{"app", "-p", "9000", "greet", "-n", "John"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	want := map[string]Source{
+		"port":    SourceArgv,
+		"workers": SourceConfig,
+		"host":    SourceEnv,
+		"retries": SourceDefault,
+		"file":    SourceDefault,
+	}
+
+	for name, source := range want {
+		flag := cli.Lookup(name)
+		if flag == nil {
+			t.Fatalf("Expected Lookup(%q) to find the flag", name)
+		}
+
+		if flag.Source() != source || flag.Changed() != (source != SourceDefault) {
+			t.Errorf("%s: expected source %s, but got %s", name, source, flag.Source())
+		}
+	}
+
+	if flag := cmd.Lookup("n"); flag == nil || flag.Source() != SourceArgv {
+		t.Errorf("Expected subcommand flag from argv, got %v", flag)
+	}
+
+	if cli.Lookup("name") != nil || cli.Lookup("missing") != nil {
+		t.Errorf("Expected Lookup to only find global flags and arguments")
+	}
+
+	// Values set by the application are parsed, validated and recorded.
+	retriesFlag := cli.Lookup("retries")
+	if err := retriesFlag.SetValue("0", SourcePrompt); err == nil || !strings.Contains(err.Error(), "invalid value (0)") {
+		t.Errorf("Expected validation error, but got %v", err)
+	}
+
+	if err := retriesFlag.SetValue("3", SourcePrompt); err != nil || retries != 3 || retriesFlag.Source() != SourcePrompt {
+		t.Errorf("Expected retries 3 from prompt, got %d from %s (%v)", retries, retriesFlag.Source(), err)
+	}
+
+	// Sources are reset on every Parse.
+	if _, err := cli.Parse([]string{"app", "a.txt"}); err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if cli.Lookup("port").Changed() || cli.Lookup("retries").Changed() || cmd.Lookup("name").Changed() {
+		t.Errorf("Expected sources to be reset")
+	}
+
+	if cli.Lookup("file").Source() != SourceArgv {
+		t.Errorf("Expected argument source argv, but got %s", cli.Lookup("file").Source())
+	}
+}
@@ -1,36 +1,282 @@
 package goflag
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"reflect"
+	"slices"
+	"strings"
 )
 
-// A subcommand. It can have its own flags.
+// A subcommand. It can have its own flags and nested subcommands.
 type subcommand struct {
-	name        string  // Subcommand name. used as a key to find the subcommand.
-	description string  // Description of what this subcommand does.
-	Handler     func()  // Subcommand callback handler. Will be invoked by user if it matches.
-	flags       []*Flag // subcommand flags.
+	name        string        // Subcommand name. used as a key to find the subcommand.
+	description string        // Description of what this subcommand does.
+	Handler     func()        // Subcommand callback handler. Will be invoked by user if it matches.
+	flags       []*Flag       // subcommand flags.
+	args        []*Flag       // subcommand positional arguments.
+	last        *Flag         // last flag or argument added to the chain.
+	operands    []string      // positional tokens from the last Parse.
+	rest        []string      // tokens after the -- terminator from the last Parse.
+	parent      *subcommand   // parent subcommand. nil for top-level subcommands.
+	subcommands []*subcommand // nested subcommands.
+	handler     HandlerFunc   // context-aware handler. Takes precedence over Handler.
+	cli         *CLI          // CLI the subcommand belongs to.
+	constraints []constraint  // constraints between the flags of the subcommand.
 }
 
-// Add validator to last flag in the subcommand chain.
-func (cmd *subcommand) Validate(validators ...FlagValidator) *subcommand {
+// Command is a subcommand of a CLI.
+// It is passed to context-aware handlers registered with CLI.Command.
+type Command = subcommand
+
+// HandlerFunc is a context-aware subcommand handler.
+// The context is canceled on SIGINT or SIGTERM when the CLI is run with Execute.
+// A returned error is mapped to the exit code of the process, see ExitCode.
+type HandlerFunc func(ctx context.Context, cmd *Command) error
+
+// Create a subcommand with the help flag.
+func newSubCommand(name, description string) *subcommand {
+	if name == "" {
+		panic("subcommand name can't be empty")
+	}
+	if description == "" {
+		panic("subcommand description can't be empty")
+	}
+
+	return &subcommand{
+		name:        name,
+		description: description,
+		flags: []*Flag{
+			{name: "help", shortName: "h", flagType: flagString, usage: "Print help message and exit"},
+		},
+	}
+}
+
+// Create a subcommand with a plain handler.
+func newHandlerSubCommand(cli *CLI, name, description string, handler func()) *subcommand {
+	if handler == nil {
+		panic("subcommand can not be registered with nil handler")
+	}
+
+	cmd := newSubCommand(name, description)
+	cmd.cli = cli
+	cmd.Handler = handler
+	return cmd
+}
+
+// Create a subcommand with a context-aware handler.
+// The Handler field calls it with a background context for callers that
+// dispatch subcommands themselves. The error is printed to the error writer
+// and passed to the exit function of the CLI only if one was set with
+// SetExitFunc; os.Exit is never called. Use Run to get the error instead.
+func newContextSubCommand(cli *CLI, name, description string, handler HandlerFunc) *subcommand {
+	if handler == nil {
+		panic("subcommand can not be registered with nil handler")
+	}
+
+	cmd := newSubCommand(name, description)
+	cmd.cli = cli
+	cmd.handler = handler
+	cmd.Handler = func() {
+		cmd.cli.handlerExit(cmd.Run(context.Background()))
+	}
+	return cmd
+}
+
+// SubCommand adds a nested subcommand and returns it, so that the chain
+// continues on the child. e.g myapp db migrate.
+//
+// The child inherits the flags of this subcommand and its ancestors.
+// They may be given anywhere after the subcommand that defines them.
+func (cmd *subcommand) SubCommand(name, description string, handler func()) *subcommand {
+	return cmd.addSubCommand(newHandlerSubCommand(cmd.cli, name, description, handler))
+}
+
+// Command adds a nested subcommand with a context-aware handler and returns it.
+// See SubCommand for details on nested subcommands.
+func (cmd *subcommand) Command(name, description string, handler HandlerFunc) *subcommand {
+	return cmd.addSubCommand(newContextSubCommand(cmd.cli, name, description, handler))
+}
+
+// Add a nested subcommand.
+func (cmd *subcommand) addSubCommand(child *subcommand) *subcommand {
+	child.parent = cmd
+	cmd.subcommands = append(cmd.subcommands, child)
+	return child
+}
+
+// Run invokes the handler of the subcommand.
+// Context-aware handlers receive ctx and their error is returned.
+// Plain handlers are called and nil is returned.
+func (cmd *subcommand) Run(ctx context.Context) error {
+	if cmd.handler != nil {
+		return cmd.handler(ctx, cmd)
+	}
+
+	if cmd.Handler != nil {
+		cmd.Handler()
+	}
+	return nil
+}
+
+// Parent returns the parent subcommand or nil for a top-level subcommand.
+func (cmd *subcommand) Parent() *subcommand {
+	return cmd.parent
+}
+
+// Path returns the names of the subcommand and its ancestors separated
+// by spaces. e.g "db migrate up"
+func (cmd *subcommand) Path() string {
+	names := []string{cmd.name}
+	for p := cmd.parent; p != nil; p = p.parent {
+		names = append(names, p.name)
+	}
+	slices.Reverse(names)
+	return strings.Join(names, " ")
+}
+
+// Returns the flags accepted by the subcommand: its own flags followed by
+// the flags inherited from its ancestors. A flag shadows an inherited
+// flag with the same name.
+func (cmd *subcommand) allFlags() []*Flag {
+	flags := slices.Clone(cmd.flags)
+	for p := cmd.parent; p != nil; p = p.parent {
+		for _, flag := range p.flags {
+			if findFlag(flags, flag.name) == nil {
+				flags = append(flags, flag)
+			}
+		}
+	}
+	return flags
+}
+
+// Returns the constraints of the subcommand and its ancestors.
+func (cmd *subcommand) allConstraints() []constraint {
+	var constraints []constraint
+	for c := cmd; c != nil; c = c.parent {
+		constraints = append(constraints, c.constraints...)
+	}
+	return constraints
+}
+
+// Returns the flags inherited from the ancestors of the subcommand.
+func (cmd *subcommand) inheritedFlags() []*Flag {
+	return cmd.allFlags()[len(cmd.flags):]
+}
+
+// Returns the subcommand among cmd and its ancestors that defines flag.
+func (cmd *subcommand) flagOwner(flag *Flag) *subcommand {
+	for c := cmd; c != nil; c = c.parent {
+		if slices.Contains(c.flags, flag) {
+			return c
+		}
+	}
+	return nil
+}
+
+// Find a subcommand by name.
+func findSubCommand(cmds []*subcommand, name string) *subcommand {
+	for _, cmd := range cmds {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// Args returns the positional arguments of the subcommand from the last call
+// to Parse, including those after the -- terminator.
+func (cmd *subcommand) Args() []string {
+	return cmd.operands
+}
+
+// Rest returns the arguments that followed the -- terminator in the
+// last call to Parse.
+func (cmd *subcommand) Rest() []string {
+	return cmd.rest
+}
+
+// Returns the last flag or argument in the subcommand chain or nil.
+func (cmd *subcommand) lastFlag() *Flag {
+	if cmd.last != nil {
+		return cmd.last
+	}
+
 	if len(cmd.flags) > 0 {
-		cmd.flags[len(cmd.flags)-1].validators = append(cmd.flags[len(cmd.flags)-1].validators, validators...)
+		return cmd.flags[len(cmd.flags)-1]
+	}
+	return nil
+}
+
+// Add validator to last flag or argument in the subcommand chain.
+func (cmd *subcommand) Validate(validators ...FlagValidator) *subcommand {
+	if flag := cmd.lastFlag(); flag != nil {
+		flag.validators = append(flag.validators, validators...)
 	}
 	return cmd
 }
 
+// Mark the last flag or argument in the subcommand chain as required.
 func (cmd *subcommand) Required() *subcommand {
-	if len(cmd.flags) > 0 {
-		cmd.flags[len(cmd.flags)-1].required = true
+	if flag := cmd.lastFlag(); flag != nil {
+		flag.required = true
+	}
+	return cmd
+}
+
+// Set the environment variable of the last flag in the subcommand chain.
+// See Flag.Env.
+func (cmd *subcommand) Env(name string) *subcommand {
+	if flag := cmd.lastFlag(); flag != nil {
+		flag.env = name
+	}
+	return cmd
+}
+
+// Make the last flag in the subcommand chain negatable. See Flag.Negatable.
+func (cmd *subcommand) Negatable() *subcommand {
+	if flag := cmd.lastFlag(); flag != nil {
+		flag.Negatable()
+	}
+	return cmd
+}
+
+// Make the last flag in the subcommand chain always take the next arg as
+// its value. See Flag.ConsumeNext.
+func (cmd *subcommand) ConsumeNext() *subcommand {
+	if flag := cmd.lastFlag(); flag != nil {
+		flag.ConsumeNext()
+	}
+	return cmd
+}
+
+// Set the function that completes the values of the last flag or argument
+// in the subcommand chain at runtime. See Flag.Complete.
+func (cmd *subcommand) Complete(fn CompletionFunc) *subcommand {
+	if flag := cmd.lastFlag(); flag != nil {
+		flag.Complete(fn)
+	}
+	return cmd
+}
+
+// Complete the values of the last flag or argument in the subcommand chain
+// with the names of files with the given extensions. See Flag.CompleteFiles.
+func (cmd *subcommand) CompleteFiles(extensions ...string) *subcommand {
+	if flag := cmd.lastFlag(); flag != nil {
+		flag.CompleteFiles(extensions...)
 	}
 	return cmd
 }
 
 // Add a flag to a subcommand.
 func (cmd *subcommand) Flag(flagType flagType, name, shortName string, valuePtr any, usage string) *subcommand {
+	cmd.addFlag(flagType, name, shortName, valuePtr, usage)
+	return cmd
+}
+
+// Add a flag to a subcommand and return it.
+func (cmd *subcommand) addFlag(flagType flagType, name, shortName string, valuePtr any, usage string) *Flag {
 	flag := &Flag{
 		flagType:   flagType,
 		name:       name,
@@ -42,11 +288,21 @@ func (cmd *subcommand) Flag(flagType flagType, name, shortName string, valuePtr
 
 	validateFlag(flag)
 	cmd.flags = append(cmd.flags, flag)
+	cmd.last = flag
+	return flag
+}
+
+// Add a positional argument to a subcommand.
+// See CLI.Arg for details on supported types and variadic arguments.
+func (cmd *subcommand) Arg(name string, valuePtr any, usage string) *subcommand {
+	cmd.args, cmd.last = addArg(cmd.args, name, valuePtr, usage)
 	return cmd
 }
 
+// PrintUsage prints the help of the subcommand, including its
+// inherited flags and nested subcommands.
 func (cmd *subcommand) PrintUsage(w io.Writer) {
-	printSubCommand(cmd, w)
+	printSubCommand(cmd, w, true)
 }
 
 func validateFlag(flag *Flag) {
@@ -63,6 +319,11 @@ func validateFlag(flag *Flag) {
 		panic("flag value can't be nil")
 	}
 
+	// Custom values need not be pointers, they store the value themselves.
+	if _, ok := flag.value.(Value); ok {
+		return
+	}
+
 	valueType := reflect.TypeOf(flag.value)
 	if valueType.Kind() != reflect.Ptr {
 		panic(fmt.Errorf("flag value for %s must be a pointer, got %s", flag.name, valueType.Kind()))
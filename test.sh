@@ -1,6 +1,6 @@
 #!/bin/sh
 
-go run ./cmd/example/example.go -config configfile.yaml \
+go run ./cmd/example/example.go -config testdata/example.json \
     --verbose \
     --timeout 10s \
     --port 8080 \
@@ -16,7 +16,7 @@ go run ./cmd/example/example.go -config configfile.yaml \
     greet -name "John Doe Name" -greeting "Wagwan,"
 
 # Another subcommand
-go run ./cmd/example/example.go -config configfile.yaml \
+go run ./cmd/example/example.go -config testdata/example.json \
     --verbose \
     --timeout 10s \
     --port 8080 \
@@ -27,9 +27,9 @@ go run ./cmd/example/example.go -config configfile.yaml \
     --credentials
 
 # Another subcommand
-go run ./cmd/example/example.go -config configfile.yaml \
+go run ./cmd/example/example.go -config testdata/example.json \
     --verbose \
     --timeout 10s \
     --port 8080 \
     sleep \
-    --time 4
+    --time 4s
@@ -0,0 +1,53 @@
+#!/bin/bash
+# Dynamic bash completion for app
+# Generated by goflag
+
+_app_completion() {
+    local line="${COMP_LINE:0:COMP_POINT}"
+    local -a words lines
+    read -ra words <<< "$line"
+    if [[ -z "$line" || "$line" == *[[:space:]] ]]; then
+        words+=("")
+    fi
+    local cur="${words[${#words[@]}-1]}"
+
+    mapfile -t lines < <("${words[0]}" __complete "${words[@]:1}" 2>/dev/null)
+    (( ${#lines[@]} )) || return 0
+    local last="${lines[${#lines[@]}-1]}"
+    [[ "$last" == :* ]] || return 0
+    local directive="${last#:}"
+    unset 'lines[${#lines[@]}-1]'
+
+    local prefix=""
+    if [[ "$cur" == -*=* && "$COMP_WORDBREAKS" == *=* ]]; then
+        prefix="${cur%%=*}="
+        cur="${cur#*=}"
+    fi
+
+    COMPREPLY=()
+    local candidate
+    if (( directive & 2 )); then
+        for candidate in "${lines[@]}"; do
+            COMPREPLY+=( $(compgen -f -X "!*.$candidate" -- "$cur") )
+        done
+        COMPREPLY+=( $(compgen -d -- "$cur") )
+        return 0
+    fi
+
+    if (( directive & 4 )); then
+        COMPREPLY=( $(compgen -d -- "$cur") )
+        return 0
+    fi
+
+    for candidate in "${lines[@]}"; do
+        candidate="${candidate%%$'\t'*}"
+        COMPREPLY+=( "${candidate#"$prefix"}" )
+    done
+
+    if (( ${#COMPREPLY[@]} == 0 && !(directive & 1) )); then
+        COMPREPLY=( $(compgen -f -- "$cur") )
+    fi
+    return 0
+}
+
+complete -F _app_completion app
@@ -0,0 +1,103 @@
+#!/bin/bash
+# Bash completion for app
+# Generated by goflag
+
+_app_completion() {
+    local cur prev word subcommands flags
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    prev="${COMP_WORDS[COMP_CWORD-1]}"
+
+    # Stop completing flags after the -- terminator
+    local i
+    for (( i=1; i < COMP_CWORD; i++ )); do
+        if [[ "${COMP_WORDS[i]}" == "--" ]]; then
+            COMPREPLY=( $(compgen -f -- "$cur") )
+            return 0
+        fi
+    done
+
+    # Find the subcommand context. e.g /db/migrate
+    local cmd_path=""
+    for word in "${COMP_WORDS[@]:1:COMP_CWORD-1}"; do
+        case "$cmd_path/$word" in
+            /completion|/db|/db/migrate)
+                cmd_path="$cmd_path/$word"
+                ;;
+        esac
+    done
+
+    case "$cmd_path" in
+        "")
+            case "$prev" in
+                --config|-c)
+                    COMPREPLY=( $(compgen -f -- "$cur") )
+                    return 0
+                    ;;
+                --format|-f)
+                    COMPREPLY=( $(compgen -W "json yaml" -- "$cur") )
+                    return 0
+                    ;;
+            esac
+            subcommands="completion db"
+            flags="--help --config --color --no-color --verbose --format"
+            ;;
+        /completion)
+            case "$prev" in
+                --shell|-s)
+                    COMPREPLY=( $(compgen -W "bash zsh fish powershell nushell" -- "$cur") )
+                    return 0
+                    ;;
+            esac
+            subcommands=""
+            flags="--help --shell --install --uninstall --dynamic --system --dry-run"
+            flags=" $flags "
+            for word in "${COMP_WORDS[@]:1:COMP_CWORD-1}"; do
+                case "$word" in
+                    --install|-i)
+                        flags="${flags// --uninstall / }"
+                        ;;
+                    --uninstall|-u)
+                        flags="${flags// --install / }"
+                        ;;
+                esac
+            done
+            ;;
+        /db)
+            case "$prev" in
+                --dir|-d)
+                    COMPREPLY=( $(compgen -d -- "$cur") )
+                    return 0
+                    ;;
+                --name|-n)
+                    return 0
+                    ;;
+            esac
+            subcommands="migrate"
+            flags="--help --dir --name"
+            ;;
+        /db/migrate)
+            case "$prev" in
+                --steps|-s)
+                    return 0
+                    ;;
+                --ratio)
+                    return 0
+                    ;;
+                --dir|-d)
+                    COMPREPLY=( $(compgen -d -- "$cur") )
+                    return 0
+                    ;;
+                --name|-n)
+                    return 0
+                    ;;
+            esac
+            subcommands=""
+            flags="--help --steps --ratio --dir --name"
+            ;;
+    esac
+
+    COMPREPLY=( $(compgen -W "$subcommands $flags" -- "$cur") )
+    return 0
+}
+
+complete -F _app_completion app
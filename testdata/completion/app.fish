@@ -0,0 +1,52 @@
+# Fish completion for app
+# Generated by goflag
+
+function __app_cmd_path
+    set -l path ""
+    set -l words (commandline -opc)
+    set -e words[1]
+    for word in $words
+        test "$word" = "--"; and break
+        switch "$path/$word"
+            case /completion /db /db/migrate
+                set path "$path/$word"
+        end
+    end
+    echo "$path"
+end
+
+function __app_using_path
+    set -l path (__app_cmd_path)
+    test "$path" = "$argv[1]"
+end
+
+complete -c app -f
+
+complete -c app -n '__app_using_path ""' -a 'completion' -d 'Generate shell completion scripts'
+complete -c app -n '__app_using_path ""' -a 'db' -d 'Database commands'
+complete -c app -n '__app_using_path ""' -l 'help' -s 'h' -d 'Print help message and exit'
+complete -c app -n '__app_using_path ""' -l 'config' -s 'c' -r -F -d 'Config file'
+complete -c app -n '__app_using_path ""' -l 'color' -d 'Use "color" (it\'s nice)'
+complete -c app -n '__app_using_path ""' -l 'no-color' -d 'Turn off --color'
+complete -c app -n '__app_using_path ""' -l 'verbose' -s 'v' -d 'Verbosity'
+complete -c app -n '__app_using_path ""' -l 'format' -s 'f' -x -a 'json yaml' -d 'Output format'
+
+complete -c app -n '__app_using_path "/completion"' -l 'help' -s 'h' -d 'Print help message and exit'
+complete -c app -n '__app_using_path "/completion"' -l 'shell' -s 's' -x -a 'bash zsh fish powershell nushell' -d 'The shell to generate completions for'
+complete -c app -n '__app_using_path "/completion"' -l 'install' -s 'i' -d 'Install the completion script to the appropriate location'
+complete -c app -n '__app_using_path "/completion"' -l 'uninstall' -s 'u' -d 'Uninstall the completion script'
+complete -c app -n '__app_using_path "/completion"' -l 'dynamic' -s 'd' -d 'Generate a script that completes values at runtime [bash|zsh]'
+complete -c app -n '__app_using_path "/completion"' -l 'system' -d 'Install or uninstall the completion script system-wide'
+complete -c app -n '__app_using_path "/completion"' -l 'dry-run' -d 'Print what install or uninstall would change without changing it'
+
+complete -c app -n '__app_using_path "/db"' -a 'migrate' -d 'Run migrations'
+complete -c app -n '__app_using_path "/db"' -l 'help' -s 'h' -d 'Print help message and exit'
+complete -c app -n '__app_using_path "/db"' -l 'dir' -s 'd' -x -a '(__fish_complete_directories)' -d 'Data directory'
+complete -c app -n '__app_using_path "/db"' -l 'name' -s 'n' -x -d 'Database name'
+
+complete -c app -n '__app_using_path "/db/migrate"' -l 'help' -s 'h' -d 'Print help message and exit'
+complete -c app -n '__app_using_path "/db/migrate"' -l 'steps' -s 's' -x -d 'Number of steps'
+complete -c app -n '__app_using_path "/db/migrate"' -l 'ratio' -x -d ''
+complete -c app -n '__app_using_path "/db/migrate"' -l 'dir' -s 'd' -x -a '(__fish_complete_directories)' -d 'Data directory'
+complete -c app -n '__app_using_path "/db/migrate"' -l 'name' -s 'n' -x -d 'Database name'
+complete -c app -n '__app_using_path "/db/migrate"' -F
@@ -0,0 +1,106 @@
+# PowerShell completion for app
+# Generated by goflag
+
+Register-ArgumentCompleter -Native -CommandName 'app' -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+
+    # Find the subcommand context. e.g /db/migrate
+    $path = ''
+    $prev = ''
+    foreach ($element in $commandAst.CommandElements | Select-Object -Skip 1) {
+        if ($element.Extent.EndOffset -ge $cursorPosition) { break }
+        $word = $element.ToString()
+
+        # Stop completing flags after the -- terminator
+        if ($word -eq '--') { return }
+        if (@('/completion', '/db', '/db/migrate') -contains "$path/$word") { $path = "$path/$word" }
+        $prev = $word
+    }
+
+    function Write-Candidate([string]$text, [string]$type, [string]$tooltip) {
+        if ($text.StartsWith($wordToComplete)) {
+            [System.Management.Automation.CompletionResult]::new($text, $text, $type, $tooltip)
+        }
+    }
+
+    switch ($path) {
+        '' {
+            switch ($prev) {
+                { $_ -in '--config', '-c' } {
+                    return
+                }
+                { $_ -in '--format', '-f' } {
+                    Write-Candidate 'json' 'ParameterValue' 'json'
+                    Write-Candidate 'yaml' 'ParameterValue' 'yaml'
+                    return
+                }
+            }
+            Write-Candidate 'completion' 'Command' 'Generate shell completion scripts'
+            Write-Candidate 'db' 'Command' 'Database commands'
+            Write-Candidate '--help' 'ParameterName' 'Print help message and exit'
+            Write-Candidate '--config' 'ParameterName' 'Config file'
+            Write-Candidate '--color' 'ParameterName' 'Use "color" (it''s nice)'
+            Write-Candidate '--no-color' 'ParameterName' 'Turn off --color'
+            Write-Candidate '--verbose' 'ParameterName' 'Verbosity'
+            Write-Candidate '--format' 'ParameterName' 'Output format'
+        }
+        '/completion' {
+            switch ($prev) {
+                { $_ -in '--shell', '-s' } {
+                    Write-Candidate 'bash' 'ParameterValue' 'bash'
+                    Write-Candidate 'zsh' 'ParameterValue' 'zsh'
+                    Write-Candidate 'fish' 'ParameterValue' 'fish'
+                    Write-Candidate 'powershell' 'ParameterValue' 'powershell'
+                    Write-Candidate 'nushell' 'ParameterValue' 'nushell'
+                    return
+                }
+            }
+            Write-Candidate '--help' 'ParameterName' 'Print help message and exit'
+            Write-Candidate '--shell' 'ParameterName' 'The shell to generate completions for'
+            Write-Candidate '--install' 'ParameterName' 'Install the completion script to the appropriate location'
+            Write-Candidate '--uninstall' 'ParameterName' 'Uninstall the completion script'
+            Write-Candidate '--dynamic' 'ParameterName' 'Generate a script that completes values at runtime [bash|zsh]'
+            Write-Candidate '--system' 'ParameterName' 'Install or uninstall the completion script system-wide'
+            Write-Candidate '--dry-run' 'ParameterName' 'Print what install or uninstall would change without changing it'
+        }
+        '/db' {
+            switch ($prev) {
+                { $_ -in '--dir', '-d' } {
+                    [System.Management.Automation.CompletionCompleters]::CompleteFilename($wordToComplete) |
+                        Where-Object ResultType -eq 'ProviderContainer'
+                    return
+                }
+                { $_ -in '--name', '-n' } {
+                    return
+                }
+            }
+            Write-Candidate 'migrate' 'Command' 'Run migrations'
+            Write-Candidate '--help' 'ParameterName' 'Print help message and exit'
+            Write-Candidate '--dir' 'ParameterName' 'Data directory'
+            Write-Candidate '--name' 'ParameterName' 'Database name'
+        }
+        '/db/migrate' {
+            switch ($prev) {
+                { $_ -in '--steps', '-s' } {
+                    return
+                }
+                { $_ -in '--ratio' } {
+                    return
+                }
+                { $_ -in '--dir', '-d' } {
+                    [System.Management.Automation.CompletionCompleters]::CompleteFilename($wordToComplete) |
+                        Where-Object ResultType -eq 'ProviderContainer'
+                    return
+                }
+                { $_ -in '--name', '-n' } {
+                    return
+                }
+            }
+            Write-Candidate '--help' 'ParameterName' 'Print help message and exit'
+            Write-Candidate '--steps' 'ParameterName' 'Number of steps'
+            Write-Candidate '--ratio' 'ParameterName' 'ratio'
+            Write-Candidate '--dir' 'ParameterName' 'Data directory'
+            Write-Candidate '--name' 'ParameterName' 'Database name'
+        }
+    }
+}
@@ -34,7 +34,7 @@ func MaxStringLen(length int) func(v any) (bool, string) {
 	return func(v any) (bool, string) {
 		s, ok := v.(string)
 		if !ok {
-			return false, "MinStringLen must be used only with strings"
+			return false, "MaxStringLen must be used only with strings"
 		}
 
 		return len(s) <= length, ""
@@ -43,21 +43,30 @@ func MaxStringLen(length int) func(v any) (bool, string) {
 
 func Max[T cmp.Ordered](maxValue T) func(v any) (bool, string) {
 	return func(v any) (bool, string) {
-		value := v.(T)
+		value, ok := v.(T)
+		if !ok {
+			return false, fmt.Sprintf("Max(%v) can not be used with %T values", maxValue, v)
+		}
 		return value <= maxValue, fmt.Sprintf("value %v is greater than maximum value: %v", v, maxValue)
 	}
 }
 
 func Min[T cmp.Ordered](minValue T) func(v any) (bool, string) {
 	return func(v any) (bool, string) {
-		value := v.(T)
+		value, ok := v.(T)
+		if !ok {
+			return false, fmt.Sprintf("Min(%v) can not be used with %T values", minValue, v)
+		}
 		return value >= minValue, fmt.Sprintf("value %v is less than minimum value: %v", v, minValue)
 	}
 }
 
 func Range[T cmp.Ordered](minValue, maxValue T) func(v any) (bool, string) {
 	return func(v any) (bool, string) {
-		value := v.(T)
+		value, ok := v.(T)
+		if !ok {
+			return false, fmt.Sprintf("Range(%v, %v) can not be used with %T values", minValue, maxValue, v)
+		}
 		return value >= minValue && value <= maxValue, fmt.Sprintf("value %v is not in range [%v, %v]", v, minValue, maxValue)
 	}
 }
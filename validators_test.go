@@ -1,6 +1,7 @@
 package goflag
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -86,3 +87,13 @@ func TestRange(t *testing.T) {
 		t.Errorf("Range validator passed for value greater than range")
 	}
 }
+
+func TestBoundsWithMismatchedType(t *testing.T) {
+	validators := []FlagValidator{Max(10), Min(1), Range(1, 10)}
+	for _, validator := range validators {
+		valid, msg := validator("5")
+		if valid || !strings.Contains(msg, "can not be used with string values") {
+			t.Errorf("Expected mismatched type to be rejected, but got %v, %q", valid, msg)
+		}
+	}
+}
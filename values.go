@@ -0,0 +1,463 @@
+package goflag
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Value is the interface to the value of a flag. Implement it to add flags
+// of custom types with CLI.Var or subcommand.Var. e.g AWS regions or semver.
+//
+// All built-in flag types are implemented on this interface.
+type Value interface {
+	// Set parses the string and stores the result.
+	Set(value string) error
+
+	// String returns the current value. Used as the default in the help.
+	String() string
+
+	// Type returns the name of the value type. e.g "region"
+	// Shown in shell completions as the value placeholder.
+	Type() string
+}
+
+// A Value that implements IsBoolFlag and returns true is set to "true" when its
+// flag is given without a value, like a bool flag. e.g --dry-run
+type boolFlag interface {
+	IsBoolFlag() bool
+}
+
+// Returns the Value of a built-in flag type bound to valuePtr
+// or nil if the type is not supported or does not match valuePtr.
+func newValue(flagType flagType, valuePtr any) Value {
+	switch flagType {
+	case flagString:
+		if p, ok := valuePtr.(*string); ok {
+			return (*stringValue)(p)
+		}
+	case flagInt:
+		if p, ok := valuePtr.(*int); ok {
+			return (*intValue)(p)
+		}
+	case flagInt64:
+		if p, ok := valuePtr.(*int64); ok {
+			return (*int64Value)(p)
+		}
+	case flagFloat32:
+		if p, ok := valuePtr.(*float32); ok {
+			return (*float32Value)(p)
+		}
+	case flagFloat64:
+		if p, ok := valuePtr.(*float64); ok {
+			return (*float64Value)(p)
+		}
+	case flagBool:
+		if p, ok := valuePtr.(*bool); ok {
+			return (*boolValue)(p)
+		}
+	case flagRune:
+		if p, ok := valuePtr.(*rune); ok {
+			return (*runeValue)(p)
+		}
+	case flagDuration:
+		if p, ok := valuePtr.(*time.Duration); ok {
+			return (*durationValue)(p)
+		}
+	case flagTime:
+		if p, ok := valuePtr.(*time.Time); ok {
+			return (*timeValue)(p)
+		}
+	case flagIP:
+		if p, ok := valuePtr.(*net.IP); ok {
+			return (*ipValue)(p)
+		}
+	case flagMAC:
+		if p, ok := valuePtr.(*net.HardwareAddr); ok {
+			return (*macValue)(p)
+		}
+	case flagURL:
+		if p, ok := valuePtr.(*url.URL); ok {
+			return (*urlValue)(p)
+		}
+	case flagUUID:
+		if p, ok := valuePtr.(*uuid.UUID); ok {
+			return (*uuidValue)(p)
+		}
+	case flagHostPortPair:
+		if p, ok := valuePtr.(*string); ok {
+			return (*hostPortValue)(p)
+		}
+	case flagEmail:
+		if p, ok := valuePtr.(*string); ok {
+			return (*emailValue)(p)
+		}
+	case flagFilePath:
+		if p, ok := valuePtr.(*string); ok {
+			return (*filePathValue)(p)
+		}
+	case flagDirPath:
+		if p, ok := valuePtr.(*string); ok {
+			return (*dirPathValue)(p)
+		}
+	case flagCount:
+		if p, ok := valuePtr.(*int); ok {
+			return (*countValue)(p)
+		}
+	case flagRegistered:
+		return newParsedValue(valuePtr)
+	case flagCustom:
+		if v, ok := valuePtr.(Value); ok {
+			return v
+		}
+	}
+	return nil
+}
+
+type stringValue string
+
+func (s *stringValue) Set(value string) error {
+	*s = stringValue(value)
+	return nil
+}
+
+func (s *stringValue) String() string { return string(*s) }
+func (s *stringValue) Type() string   { return "string" }
+
+type intValue int
+
+func (i *intValue) Set(value string) error {
+	v, err := ParseInt(value)
+	if err != nil {
+		return err
+	}
+	*i = intValue(v)
+	return nil
+}
+
+func (i *intValue) String() string { return strconv.Itoa(int(*i)) }
+func (i *intValue) Type() string   { return "int" }
+
+// A count flag given with a value, e.g --verbose=3 or from the environment.
+// Occurrences on the command line are counted by Parse.
+type countValue int
+
+func (c *countValue) Set(value string) error {
+	v, err := ParseInt(value)
+	if err != nil {
+		return err
+	}
+
+	if v < 0 {
+		return fmt.Errorf("count can not be negative: %d", v)
+	}
+	*c = countValue(v)
+	return nil
+}
+
+func (c *countValue) String() string { return strconv.Itoa(int(*c)) }
+func (c *countValue) Type() string   { return "count" }
+
+type int64Value int64
+
+func (i *int64Value) Set(value string) error {
+	v, err := ParseInt64(value)
+	if err != nil {
+		return err
+	}
+	*i = int64Value(v)
+	return nil
+}
+
+func (i *int64Value) String() string { return strconv.FormatInt(int64(*i), 10) }
+func (i *int64Value) Type() string   { return "int64" }
+
+type float32Value float32
+
+func (f *float32Value) Set(value string) error {
+	v, err := ParseFloat32(value)
+	if err != nil {
+		return err
+	}
+	*f = float32Value(v)
+	return nil
+}
+
+func (f *float32Value) String() string { return strconv.FormatFloat(float64(*f), 'g', -1, 32) }
+func (f *float32Value) Type() string   { return "float32" }
+
+type float64Value float64
+
+func (f *float64Value) Set(value string) error {
+	v, err := ParseFloat64(value)
+	if err != nil {
+		return err
+	}
+	*f = float64Value(v)
+	return nil
+}
+
+func (f *float64Value) String() string { return strconv.FormatFloat(float64(*f), 'g', -1, 64) }
+func (f *float64Value) Type() string   { return "float64" }
+
+type boolValue bool
+
+func (b *boolValue) Set(value string) error {
+	v, err := ParseBool(value)
+	if err != nil {
+		return err
+	}
+	*b = boolValue(v)
+	return nil
+}
+
+func (b *boolValue) String() string   { return strconv.FormatBool(bool(*b)) }
+func (b *boolValue) Type() string     { return "bool" }
+func (b *boolValue) IsBoolFlag() bool { return true }
+
+type runeValue rune
+
+func (r *runeValue) Set(value string) error {
+	v, err := ParseRune(value)
+	if err != nil {
+		return err
+	}
+	*r = runeValue(v)
+	return nil
+}
+
+func (r *runeValue) String() string { return string(rune(*r)) }
+func (r *runeValue) Type() string   { return "rune" }
+
+type durationValue time.Duration
+
+func (d *durationValue) Set(value string) error {
+	v, err := ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = durationValue(v)
+	return nil
+}
+
+func (d *durationValue) String() string { return time.Duration(*d).String() }
+func (d *durationValue) Type() string   { return "duration" }
+
+// Returns the Value of a slice or map flag or nil if the flag is neither or
+// its type does not match its value pointer. Set parses the value like the
+// command line, with the separator, duplicate key policy and value validators
+// of the flag.
+func newListValue(flag *Flag) Value {
+	switch flag.flagType {
+	case flagStringSlice:
+		if _, ok := flag.value.(*[]string); ok {
+			return &sliceValue{flag: flag, typeName: "strings"}
+		}
+	case flagIntSlice:
+		if _, ok := flag.value.(*[]int); ok {
+			return &sliceValue{flag: flag, typeName: "ints"}
+		}
+	case flagStringMap:
+		if _, ok := flag.value.(*map[string]string); ok {
+			return &mapValue{flag: flag, typeName: "key=value"}
+		}
+	case flagIntMap:
+		if _, ok := flag.value.(*map[string]int); ok {
+			return &mapValue{flag: flag, typeName: "key=int"}
+		}
+	case flagDurationMap:
+		if _, ok := flag.value.(*map[string]time.Duration); ok {
+			return &mapValue{flag: flag, typeName: "key=duration"}
+		}
+	}
+	return nil
+}
+
+// Returns the separator of the elements of a slice or map flag.
+func (flag *Flag) listSeparator() string {
+	if flag.separator == 0 {
+		return ","
+	}
+	return string(flag.separator)
+}
+
+// The Value of a slice flag. Set replaces the elements.
+type sliceValue struct {
+	flag     *Flag
+	typeName string
+}
+
+func (s *sliceValue) Set(value string) error { return setSliceValue(s.flag, value, false) }
+
+func (s *sliceValue) String() string {
+	slice := reflect.ValueOf(s.flag.value).Elem()
+	parts := make([]string, slice.Len())
+	for i := range parts {
+		parts[i] = fmt.Sprint(slice.Index(i).Interface())
+	}
+	return strings.Join(parts, s.flag.listSeparator())
+}
+
+func (s *sliceValue) Type() string { return s.typeName }
+
+// The Value of a map flag. Set replaces the pairs.
+type mapValue struct {
+	flag     *Flag
+	typeName string
+}
+
+func (m *mapValue) Set(value string) error { return setMapValue(m.flag, value, false) }
+
+// Format the map as sorted key=value pairs. e.g a=1,b=2
+func (m *mapValue) String() string {
+	current := reflect.ValueOf(m.flag.value).Elem()
+	keys := current.MapKeys()
+	slices.SortFunc(keys, func(a, b reflect.Value) int { return strings.Compare(a.String(), b.String()) })
+
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = fmt.Sprintf("%s=%v", key, current.MapIndex(key))
+	}
+	return strings.Join(parts, m.flag.listSeparator())
+}
+
+func (m *mapValue) Type() string { return m.typeName }
+
+type timeValue time.Time
+
+func (t *timeValue) Set(value string) error {
+	v, err := ParseTime(value)
+	if err != nil {
+		return err
+	}
+	*t = timeValue(v)
+	return nil
+}
+
+func (t *timeValue) String() string { return time.Time(*t).String() }
+func (t *timeValue) Type() string   { return "time" }
+
+type ipValue net.IP
+
+func (ip *ipValue) Set(value string) error {
+	v, err := ParseIP(value)
+	if err != nil {
+		return err
+	}
+	*ip = ipValue(v)
+	return nil
+}
+
+func (ip *ipValue) String() string { return net.IP(*ip).String() }
+func (ip *ipValue) Type() string   { return "ip" }
+
+type macValue net.HardwareAddr
+
+func (mac *macValue) Set(value string) error {
+	v, err := ParseMAC(value)
+	if err != nil {
+		return err
+	}
+	*mac = macValue(v)
+	return nil
+}
+
+func (mac *macValue) String() string { return net.HardwareAddr(*mac).String() }
+func (mac *macValue) Type() string   { return "mac" }
+
+type urlValue url.URL
+
+func (u *urlValue) Set(value string) error {
+	v, err := ParseUrl(value)
+	if err != nil {
+		return err
+	}
+	*u = urlValue(*v)
+	return nil
+}
+
+func (u *urlValue) String() string { return (*url.URL)(u).String() }
+func (u *urlValue) Type() string   { return "url" }
+
+type uuidValue uuid.UUID
+
+func (id *uuidValue) Set(value string) error {
+	v, err := ParseUUID(value)
+	if err != nil {
+		return err
+	}
+	*id = uuidValue(v)
+	return nil
+}
+
+func (id *uuidValue) String() string { return uuid.UUID(*id).String() }
+func (id *uuidValue) Type() string   { return "uuid" }
+
+type hostPortValue string
+
+func (hp *hostPortValue) Set(value string) error {
+	v, err := ParseHostPort(value)
+	if err != nil {
+		return err
+	}
+	*hp = hostPortValue(v)
+	return nil
+}
+
+func (hp *hostPortValue) String() string { return string(*hp) }
+func (hp *hostPortValue) Type() string   { return "host:port" }
+
+type emailValue string
+
+func (e *emailValue) Set(value string) error {
+	v, err := ParseEmail(value)
+	if err != nil {
+		return err
+	}
+	*e = emailValue(v)
+	return nil
+}
+
+func (e *emailValue) String() string { return string(*e) }
+func (e *emailValue) Type() string   { return "email" }
+
+type filePathValue string
+
+func (p *filePathValue) Set(value string) error {
+	// A lone - stands for stdin or stdout by convention and is kept as is.
+	if value == "-" {
+		*p = "-"
+		return nil
+	}
+
+	v, err := ParseFilePath(value)
+	if err != nil {
+		return err
+	}
+	*p = filePathValue(v)
+	return nil
+}
+
+func (p *filePathValue) String() string { return string(*p) }
+func (p *filePathValue) Type() string   { return "file" }
+
+type dirPathValue string
+
+func (p *dirPathValue) Set(value string) error {
+	v, err := ParseDirPath(value)
+	if err != nil {
+		return err
+	}
+	*p = dirPathValue(v)
+	return nil
+}
+
+func (p *dirPathValue) String() string { return string(*p) }
+func (p *dirPathValue) Type() string   { return "dir" }
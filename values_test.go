@@ -0,0 +1,121 @@
+package goflag
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A custom Value that only accepts known regions.
+type region string
+
+func (r *region) Set(value string) error {
+	if !slices.Contains([]string{"us-east-1", "eu-west-1"}, value) {
+		return fmt.Errorf("unknown region %s", value)
+	}
+	*r = region(value)
+	return nil
+}
+
+func (r *region) String() string { return string(*r) }
+func (r *region) Type() string   { return "region" }
+
+// A custom bool-like Value.
+type toggle struct{ on bool }
+
+func (t *toggle) Set(value string) error {
+	v, err := ParseBool(value)
+	t.on = v
+	return err
+}
+
+func (t *toggle) String() string   { return fmt.Sprint(t.on) }
+func (t *toggle) Type() string     { return "toggle" }
+func (t *toggle) IsBoolFlag() bool { return true }
+
+func TestVar(t *testing.T) {
+	home := region("us-east-1")
+	var target region
+	var debug toggle
+	var file string
+
+	cli := New()
+	cli.Var("region", "r", &home, "Home region")
+	cli.Var("debug", "d", &debug, "Debug")
+	cli.SubCommand("copy", "Copy objects", func() {}).
+		Var("target", "t", &target, "Target region").Required().
+		Validate(func(value any) (bool, string) {
+			return value.(*region).String() != home.String(), "target must differ from home"
+		}).
+		Arg("file", &file, "File to copy")
+
+	cmd, err := cli.Parse([]string{"app", "-d", "--region", "eu-west-1", "copy", "-t", "us-east-1", "a.txt"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got '%v'", err)
+	}
+
+	if cmd.name != "copy" || home != "eu-west-1" || target != "us-east-1" || !debug.on || file != "a.txt" {
+		t.Errorf("Unexpected values: home=%s target=%s debug=%v file=%s", home, target, debug.on, file)
+	}
+
+	_, err = cli.Parse([]string{"app", "--region", "mars-1"})
+	if err == nil || !strings.Contains(err.Error(), "unknown region mars-1") {
+		t.Errorf("Expected error from Set, but got %v", err)
+	}
+
+	_, err = cli.Parse([]string{"app", "--region", "us-east-1", "copy", "-t", "us-east-1"})
+	if err == nil || !strings.Contains(err.Error(), "target must differ from home") {
+		t.Errorf("Expected validator error, but got %v", err)
+	}
+
+	// The help shows the default from String.
+	var out bytes.Buffer
+	cli.SetOutput(&out)
+	cli.PrintUsage(&out)
+	if !strings.Contains(out.String(), "(default: us-east-1)") {
+		t.Errorf("Expected default region in help, got:\n%s", out.String())
+	}
+
+	// Completions show the type as the value placeholder.
+	out.Reset()
+	cli.GenZshCompletion(&out)
+	if !strings.Contains(out.String(), "'--region[Home region]:region:'") {
+		t.Errorf("Expected region placeholder in zsh completion, got:\n%s", out.String())
+	}
+}
+
+func TestBuiltinValues(t *testing.T) {
+	var (
+		d   = 5 * time.Second
+		ips = net.ParseIP("127.0.0.1")
+		s   = []string{"a", "b"}
+		n   = []int{1, 2}
+	)
+
+	tests := []struct {
+		flag     *Flag
+		typeName string
+		want     string
+	}{
+		{&Flag{flagType: flagDuration, value: &d}, "duration", "5s"},
+		{&Flag{flagType: flagIP, value: &ips}, "ip", "127.0.0.1"},
+		{&Flag{flagType: flagStringSlice, value: &s}, "strings", "a,b"},
+		{&Flag{flagType: flagIntSlice, value: &n}, "ints", "1,2"},
+	}
+
+	for _, test := range tests {
+		v := test.flag.Value()
+		if v.Type() != test.typeName || v.String() != test.want {
+			t.Errorf("Expected %s %q, but got %s %q", test.typeName, test.want, v.Type(), v.String())
+		}
+
+		// Set stores through the value pointer.
+		if err := v.Set(test.want); err != nil || v.String() != test.want {
+			t.Errorf("Set(%q) = %v, String() = %q", test.want, err, v.String())
+		}
+	}
+}